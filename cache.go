@@ -0,0 +1,57 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// readSessionCache loads the session from the cache file and checks that it
+// is still valid. It returns false if there is no usable cached session,
+// including one cached for a different account. v2 names session variables
+// after the user rather than the account, so the account is stored on the
+// first line of the file.
+func (o *Op) readSessionCache(ctx context.Context) (bool, error) {
+	data, err := ioutil.ReadFile(o.sessionCache)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("unable to read session cache %s: %v", o.sessionCache, err)
+	}
+	lines := strings.SplitN(strings.TrimSpace(string(data)), "\n", 2)
+	if len(lines) != 2 || lines[0] != o.account {
+		return false, nil
+	}
+	parts := strings.SplitN(strings.TrimSpace(lines[1]), "=", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], o.envPrefix) || parts[1] == "" {
+		return false, nil
+	}
+	o.setSession(parts[0], fmt.Sprintf("%s=%s", parts[0], parts[1]))
+
+	if err := o.checkSession(ctx); err != nil {
+		o.setSession(parts[0], "")
+		if errors.Is(err, ErrSessionExpired) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// writeSessionCache saves the active session to the cache file
+func (o *Op) writeSessionCache() error {
+	_, setEnv := o.session()
+	data := fmt.Sprintf("%s\n%s\n", o.account, setEnv)
+	if err := ioutil.WriteFile(o.sessionCache, []byte(data), 0600); err != nil {
+		return fmt.Errorf("unable to write session cache %s: %v", o.sessionCache, err)
+	}
+	// WriteFile doesn't change the permissions of an existing file
+	if err := os.Chmod(o.sessionCache, 0600); err != nil {
+		return fmt.Errorf("unable to set permissions on session cache %s: %v", o.sessionCache, err)
+	}
+	return nil
+}
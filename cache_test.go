@@ -0,0 +1,67 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionCache(t *testing.T) {
+	configImpl = mockConfiger{}
+	path := filepath.Join(t.TempDir(), "session")
+	var got []string
+	recordCmd := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		got = append(got, args[0])
+		return mockCmd(ctx, name, args...)
+	}
+	tests := []struct {
+		name   string
+		cached string
+		want   string
+	}{
+		{"NoCache", "", "[--version signin]"},
+		{"ValidCache", "my_team\nOP_SESSION_my_team=RANDO\n", "[--version list]"},
+		{"StaleCache", "my_team\nOP_SESSION_my_team=STALE", "[--version list signin]"},
+		{"OtherAccount", "other_account\nOP_SESSION_other_account=TOKEN", "[--version signin]"},
+		{"NoAccount", "OP_SESSION_other_account=TOKEN", "[--version signin]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			os.Remove(path)
+			if tt.cached != "" {
+				if err := ioutil.WriteFile(path, []byte(tt.cached), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+			o, err := New(WithSessionCache(path), WithRunner(recordCmd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Fatalf("Got commands: %v, want: %s\n", got, tt.want)
+			}
+			if o.setEnv != "OP_SESSION_my_team=RANDO" {
+				t.Fatalf("Got session: %s\n", o.setEnv)
+			}
+			cached, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(cached) != "my_team\nOP_SESSION_my_team=RANDO\n" {
+				t.Fatalf("Got cache: %q\n", cached)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Fatalf("Got permissions: %v, want: 0600\n", info.Mode().Perm())
+			}
+		})
+	}
+}
@@ -0,0 +1,459 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// categories maps the template uuids used by v1 to category names
+var categories = map[string]string{
+	"001": "Login",
+	"002": "Credit Card",
+	"003": "Secure Note",
+	"004": "Identity",
+	"005": "Password",
+	"100": "Software License",
+	"101": "Bank Account",
+	"102": "Database",
+	"103": "Driver License",
+	"104": "Outdoor License",
+	"105": "Membership",
+	"106": "Passport",
+	"107": "Reward Program",
+	"108": "Social Security Number",
+	"109": "Wireless Router",
+	"110": "Server",
+	"111": "Email Account",
+	"112": "API Credential",
+	"113": "Medical Record",
+	"114": "SSH Key",
+}
+
+// templateUUID returns the v1 template uuid of a v2 category such as
+// CREDIT_CARD
+func templateUUID(category string) string {
+	for uuid, name := range categories {
+		if strings.ToUpper(strings.ReplaceAll(name, " ", "_")) == category {
+			return uuid
+		}
+	}
+	return ""
+}
+
+// field returns the value of the first field whose id or label is one of
+// names. Fields in sections are searched as well as top-level fields.
+func (i opItem) field(names ...string) string {
+	for _, name := range names {
+		for _, f := range i.Details.Fields {
+			if f.Name == name {
+				return f.Value
+			}
+		}
+		for _, s := range i.Details.Sections {
+			for _, f := range s.Fields {
+				if f.N == name || f.T == name {
+					return f.V
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// getCategory returns an item, or an error wrapping ErrItemNotFound if the
+// item isn't in the given category
+func (o *Op) getCategory(item, category string) (opItem, error) {
+	i, err := o.getItem(context.Background(), item)
+	if err != nil {
+		return opItem{}, err
+	}
+	if categories[i.TemplateUUID] != category {
+		return opItem{}, fmt.Errorf("'%s' is not a %s item: %w", item, category, ErrItemNotFound)
+	}
+	return i, nil
+}
+
+// CreditCard holds the details of a credit card item
+type CreditCard struct {
+	Cardholder string
+	Type       string
+	Number     string
+	CVV        string
+	// Expiry is formatted as MM/YYYY
+	Expiry string
+}
+
+// GetCreditCard returns the details of a credit card item. An error wrapping
+// ErrItemNotFound is returned if the item isn't a credit card.
+func (o *Op) GetCreditCard(item string) (CreditCard, error) {
+	i, err := o.getCategory(item, "Credit Card")
+	if err != nil {
+		return CreditCard{}, err
+	}
+	return CreditCard{
+		Cardholder: i.field("cardholder", "cardholder name"),
+		Type:       i.field("type"),
+		Number:     i.field("ccnum", "number"),
+		CVV:        i.field("cvv", "verification number"),
+		Expiry:     monthYear(i.field("expiry", "expiry date")),
+	}, nil
+}
+
+// String describes the card without revealing its number or CVV so that it
+// can be logged safely
+func (c CreditCard) String() string {
+	last4 := c.Number
+	if len(last4) > 4 {
+		last4 = last4[len(last4)-4:]
+	}
+	return fmt.Sprintf("{%s %s ****%s *** %s}", c.Cardholder, c.Type, last4, c.Expiry)
+}
+
+// monthYear formats the YYYYMM dates used by v1 as MM/YYYY, as used by v2
+func monthYear(date string) string {
+	if len(date) == 6 && !strings.Contains(date, "/") {
+		return date[4:] + "/" + date[:4]
+	}
+	return date
+}
+
+// Database holds the details of a database item. Fields which aren't set are
+// empty.
+type Database struct {
+	Type     string
+	Server   string
+	Port     string
+	Database string
+	Username string
+	Password string
+	SID      string
+}
+
+// GetDatabase returns the details of a database item. An error wrapping
+// ErrItemNotFound is returned if the item isn't a database.
+func (o *Op) GetDatabase(item string) (Database, error) {
+	i, err := o.getCategory(item, "Database")
+	if err != nil {
+		return Database{}, err
+	}
+	return Database{
+		Type:     i.field("database_type", "type"),
+		Server:   i.field("hostname", "server"),
+		Port:     i.field("port"),
+		Database: i.field("database"),
+		Username: i.field("username"),
+		Password: i.field("password"),
+		SID:      i.field("sid", "SID"),
+	}, nil
+}
+
+// SSHKey holds the details of an SSH key item
+type SSHKey struct {
+	// PrivateKey is returned exactly as stored, including the trailing
+	// newline of a PEM block
+	PrivateKey  string
+	PublicKey   string
+	Fingerprint string
+}
+
+// GetSSHKey returns the details of an SSH key item. An error wrapping
+// ErrItemNotFound is returned if the item isn't an SSH key.
+func (o *Op) GetSSHKey(item string) (SSHKey, error) {
+	i, err := o.getCategory(item, "SSH Key")
+	if err != nil {
+		return SSHKey{}, err
+	}
+	return SSHKey{
+		PrivateKey:  i.field("private key", "private_key"),
+		PublicKey:   i.field("public key", "public_key"),
+		Fingerprint: i.field("fingerprint"),
+	}, nil
+}
+
+// APICredential holds the details of an API credential item
+type APICredential struct {
+	Username   string
+	Credential string
+	Type       string
+	Hostname   string
+}
+
+// GetAPICredential returns the details of an API credential item. An error
+// wrapping ErrItemNotFound is returned if the item isn't an API credential.
+func (o *Op) GetAPICredential(item string) (APICredential, error) {
+	i, err := o.getCategory(item, "API Credential")
+	if err != nil {
+		return APICredential{}, err
+	}
+	return APICredential{
+		Username:   i.field("username"),
+		Credential: i.field("credential"),
+		Type:       i.field("type"),
+		Hostname:   i.field("hostname"),
+	}, nil
+}
+
+// Category is one of the built-in item categories, named as in categories
+type Category string
+
+// The categories supported by GetByCategory
+const (
+	CategoryLogin                Category = "Login"
+	CategoryCreditCard           Category = "Credit Card"
+	CategorySecureNote           Category = "Secure Note"
+	CategoryIdentity             Category = "Identity"
+	CategoryPassword             Category = "Password"
+	CategorySoftwareLicense      Category = "Software License"
+	CategoryBankAccount          Category = "Bank Account"
+	CategoryDatabase             Category = "Database"
+	CategoryDriverLicense        Category = "Driver License"
+	CategoryOutdoorLicense       Category = "Outdoor License"
+	CategoryMembership           Category = "Membership"
+	CategoryPassport             Category = "Passport"
+	CategoryRewardProgram        Category = "Reward Program"
+	CategorySocialSecurityNumber Category = "Social Security Number"
+	CategoryWirelessRouter       Category = "Wireless Router"
+	CategoryServer               Category = "Server"
+	CategoryEmailAccount         Category = "Email Account"
+	CategoryAPICredential        Category = "API Credential"
+	CategoryMedicalRecord        Category = "Medical Record"
+	CategorySSHKey               Category = "SSH Key"
+)
+
+// schemaField is a field of a category. names are the v1 id followed by the
+// v2 label, which are used to look it up with field.
+type schemaField struct {
+	key   string
+	names []string
+}
+
+// categorySchemas lists the fields of each category under the keys returned
+// by GetByCategory
+var categorySchemas = map[Category][]schemaField{
+	CategoryLogin: {
+		{"username", []string{"username"}},
+		{"password", []string{"password"}},
+	},
+	CategoryCreditCard: {
+		{"cardholder", []string{"cardholder", "cardholder name"}},
+		{"type", []string{"type"}},
+		{"number", []string{"ccnum", "number"}},
+		{"cvv", []string{"cvv", "verification number"}},
+		{"expiry", []string{"expiry", "expiry date"}},
+	},
+	CategorySecureNote: {},
+	CategoryIdentity: {
+		{"first_name", []string{"firstname", "first name"}},
+		{"last_name", []string{"lastname", "last name"}},
+		{"birth_date", []string{"birthdate", "birth date"}},
+		{"email", []string{"email"}},
+		{"phone", []string{"defphone", "phone"}},
+		{"address", []string{"address"}},
+	},
+	CategoryPassword: {
+		{"password", []string{"password"}},
+	},
+	CategorySoftwareLicense: {
+		{"version", []string{"product_version", "version"}},
+		{"license_key", []string{"reg_code", "license key"}},
+		{"licensed_to", []string{"reg_name", "licensed to"}},
+	},
+	CategoryBankAccount: {
+		{"bank_name", []string{"bankName", "bank name"}},
+		{"name_on_account", []string{"owner", "name on account"}},
+		{"type", []string{"accountType", "type"}},
+		{"routing_number", []string{"routingNo", "routing number"}},
+		{"account_number", []string{"accountNo", "account number"}},
+		{"swift", []string{"swift", "SWIFT"}},
+		{"iban", []string{"iban", "IBAN"}},
+		{"pin", []string{"telephonePin", "PIN"}},
+	},
+	CategoryDatabase: {
+		{"type", []string{"database_type", "type"}},
+		{"server", []string{"hostname", "server"}},
+		{"port", []string{"port"}},
+		{"database", []string{"database"}},
+		{"username", []string{"username"}},
+		{"password", []string{"password"}},
+		{"sid", []string{"sid", "SID"}},
+	},
+	CategoryDriverLicense: {
+		{"full_name", []string{"fullname", "full name"}},
+		{"number", []string{"number"}},
+		{"class", []string{"class", "license class"}},
+		{"state", []string{"state"}},
+		{"country", []string{"country"}},
+		{"expiry", []string{"expiry_date", "expiry date"}},
+	},
+	CategoryOutdoorLicense: {
+		{"full_name", []string{"name", "full name"}},
+		{"valid_from", []string{"valid_from", "valid from"}},
+		{"expires", []string{"expires"}},
+		{"country", []string{"country"}},
+	},
+	CategoryMembership: {
+		{"group", []string{"org_name", "group"}},
+		{"member_name", []string{"member_name", "member name"}},
+		{"member_id", []string{"membership_no", "member ID"}},
+		{"pin", []string{"pin", "PIN"}},
+	},
+	CategoryPassport: {
+		{"type", []string{"type"}},
+		{"issuing_country", []string{"issuing_country", "issuing country"}},
+		{"number", []string{"number"}},
+		{"full_name", []string{"fullname", "full name"}},
+		{"nationality", []string{"nationality"}},
+		{"birth_date", []string{"birthdate", "date of birth"}},
+		{"issued_on", []string{"issue_date", "issued on"}},
+		{"expiry", []string{"expiry_date", "expiry date"}},
+	},
+	CategoryRewardProgram: {
+		{"company", []string{"company_name", "company name"}},
+		{"member_name", []string{"member_name", "member name"}},
+		{"member_id", []string{"membership_no", "member ID"}},
+		{"pin", []string{"pin", "PIN"}},
+	},
+	CategorySocialSecurityNumber: {
+		{"name", []string{"name"}},
+		{"number", []string{"number"}},
+	},
+	CategoryWirelessRouter: {
+		{"base_station_name", []string{"name", "base station name"}},
+		{"base_station_password", []string{"password", "base station password"}},
+		{"server", []string{"server", "server / IP address"}},
+		{"network_name", []string{"network_name", "network name"}},
+		{"wireless_security", []string{"wireless_security", "wireless security"}},
+		{"wireless_password", []string{"wireless_password", "wireless network password"}},
+	},
+	CategoryServer: {
+		{"url", []string{"url", "URL"}},
+		{"username", []string{"username"}},
+		{"password", []string{"password"}},
+	},
+	CategoryEmailAccount: {
+		{"type", []string{"pop_type", "type"}},
+		{"username", []string{"pop_username", "username"}},
+		{"server", []string{"pop_server", "server"}},
+		{"port", []string{"pop_port", "port number"}},
+		{"password", []string{"pop_password", "password"}},
+	},
+	CategoryAPICredential: {
+		{"username", []string{"username"}},
+		{"credential", []string{"credential"}},
+		{"type", []string{"type"}},
+		{"hostname", []string{"hostname"}},
+	},
+	CategoryMedicalRecord: {
+		{"date", []string{"date"}},
+		{"location", []string{"location"}},
+		{"healthcare_professional", []string{"healthcareprofessional", "healthcare professional"}},
+		{"patient", []string{"patient"}},
+		{"reason", []string{"reason", "reason for visit"}},
+	},
+	CategorySSHKey: {
+		{"private_key", []string{"private key", "private_key"}},
+		{"public_key", []string{"public key", "public_key"}},
+		{"fingerprint", []string{"fingerprint"}},
+	},
+}
+
+// GetByCategory returns the fields of an item in category under the keys
+// used by its schema, such as "network_name" for a wireless router. Every key
+// of the schema is present, with fields which aren't set left empty, along
+// with "notes" holding the item's notes. An error wrapping ErrItemNotFound is
+// returned if the item isn't in category.
+func (o *Op) GetByCategory(item string, category Category) (map[string]string, error) {
+	schema, ok := categorySchemas[category]
+	if !ok {
+		return nil, fmt.Errorf("unsupported category '%s'", category)
+	}
+	i, err := o.getCategory(item, string(category))
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]string, len(schema)+1)
+	for _, f := range schema {
+		fields[f.key] = i.field(f.names...)
+	}
+	if category == CategoryCreditCard {
+		fields["expiry"] = monthYear(fields["expiry"])
+	}
+	fields["notes"] = i.Details.NotesPlain
+	return fields, nil
+}
+
+// validateItem checks that an item to be created has a title and that its
+// category is one op knows, rather than leaving op to reject it
+func validateItem(title, category string) error {
+	if strings.TrimSpace(title) == "" {
+		return fmt.Errorf("an item title is required")
+	}
+	valid := make([]string, 0, len(categories))
+	for _, name := range categories {
+		if name == category {
+			return nil
+		}
+		valid = append(valid, name)
+	}
+	sort.Strings(valid)
+	return fmt.Errorf("invalid category '%s', must be one of: %s", category, strings.Join(valid, ", "))
+}
+
+// Passport holds the details of a passport item. Dates which aren't set are
+// the zero time.
+type Passport struct {
+	Type           string
+	IssuingCountry string
+	Number         string
+	FullName       string
+	Nationality    string
+	BirthDate      time.Time
+	IssuedOn       time.Time
+	Expiry         time.Time
+}
+
+// GetPassport returns the details of a passport item. An error wrapping
+// ErrItemNotFound is returned if the item isn't a passport.
+func (o *Op) GetPassport(item string) (Passport, error) {
+	i, err := o.getCategory(item, "Passport")
+	if err != nil {
+		return Passport{}, err
+	}
+	p := Passport{
+		Type:           i.field("type"),
+		IssuingCountry: i.field("issuing_country", "issuing country"),
+		Number:         i.field("number"),
+		FullName:       i.field("fullname", "full name"),
+		Nationality:    i.field("nationality"),
+	}
+	dates := []struct {
+		t     *time.Time
+		names []string
+	}{
+		{&p.BirthDate, []string{"birthdate", "date of birth"}},
+		{&p.IssuedOn, []string{"issue_date", "issued on"}},
+		{&p.Expiry, []string{"expiry_date", "expiry date"}},
+	}
+	for _, d := range dates {
+		if *d.t, err = parseDate(i.field(d.names...)); err != nil {
+			return Passport{}, fmt.Errorf("unable to parse %s of '%s': %v", d.names[len(d.names)-1], item, err)
+		}
+	}
+	return p, nil
+}
+
+// parseDate parses the value of a date field, which op stores as a unix
+// timestamp, or a date such as 2030-01-31. An empty value is the zero time.
+func parseDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
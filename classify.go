@@ -0,0 +1,71 @@
+package op
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// opError is an error reported by op as JSON
+type opError struct {
+	// Code may be a string or a number
+	Code    interface{} `json:"code"`
+	Message string      `json:"message"`
+}
+
+// errorCodes maps the codes op reports in JSON errors to package errors
+var errorCodes = map[string]error{
+	"401":                     ErrSessionExpired,
+	"unauthorized":            ErrSessionExpired,
+	"authentication_required": ErrSessionExpired,
+	"session_expired":         ErrSessionExpired,
+	"404":                     ErrItemNotFound,
+	"not_found":               ErrItemNotFound,
+	"item_not_found":          ErrItemNotFound,
+	"429":                     ErrRateLimited,
+	"rate_limited":            ErrRateLimited,
+	"too_many_requests":       ErrRateLimited,
+}
+
+// parseError returns the code of the first JSON error in the output of op, or
+// an empty string if there isn't one
+func parseError(errOut string) string {
+	for _, line := range strings.Split(errOut, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "{") {
+			continue
+		}
+		var e opError
+		if err := json.Unmarshal([]byte(line), &e); err != nil || e.Code == nil {
+			continue
+		}
+		// numbers are unmarshalled as float64
+		if n, ok := e.Code.(float64); ok {
+			return fmt.Sprint(int64(n))
+		}
+		return strings.ToLower(fmt.Sprint(e.Code))
+	}
+	return ""
+}
+
+// classify returns the package error describing why op failed, and the code
+// it reported if the error was JSON. Known codes are used in preference to
+// matching the text of the error, which depends on the version and language
+// of op.
+func classify(errOut string) (error, string) {
+	code := parseError(errOut)
+	if err, ok := errorCodes[code]; ok {
+		return err, code
+	}
+	switch {
+	case authRequired.FindString(errOut) != "":
+		return ErrSessionExpired, code
+	case rateLimited.FindString(errOut) != "":
+		return ErrRateLimited, code
+	case connectionFailed.FindString(errOut) != "":
+		return ErrConnectionFailed, code
+	case doesNotExist.FindString(errOut) != "":
+		return ErrItemNotFound, code
+	}
+	return nil, code
+}
@@ -0,0 +1,83 @@
+package op
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// readSecretFile returns the contents of path with any trailing newline
+// removed. Files which can be read by other users are rejected.
+func readSecretFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %v", path, err)
+	}
+	if info.Mode().Perm()&0044 != 0 {
+		return "", fmt.Errorf("%s is readable by other users (%v), please restrict its permissions", path, info.Mode().Perm())
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s: %v", path, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// CredFiles holds the paths of files containing sign-in credentials, such as
+// secrets mounted into a container. Empty paths are ignored.
+type CredFiles struct {
+	Password  string
+	SecretKey string
+	URL       string
+	Email     string
+}
+
+// WithCredentialsFromFiles reads sign-in credentials from the given files
+// when a sign-in is required. If the secret key, URL and email are all
+// available the account is added to op as it would be by WithSecretKey,
+// WithURL and WithEmail, and the op config isn't needed. An error is returned
+// if any file is unreadable or readable by other users.
+func WithCredentialsFromFiles(files CredFiles) Opt {
+	return func(o *Op) {
+		o.credFiles = files
+		if files.Password != "" {
+			WithPasswordFile(files.Password)(o)
+		}
+	}
+}
+
+// addAccountURL returns the address of the account which will be added at
+// sign-in, or an empty string if the email, secret key and URL needed to add
+// it haven't all been given
+func (o *Op) addAccountURL() (string, error) {
+	if (o.email == "" && o.credFiles.Email == "") || (o.secretKey == "" && o.credFiles.SecretKey == "") {
+		return "", nil
+	}
+	if o.credFiles.URL != "" {
+		return readSecretFile(o.credFiles.URL)
+	}
+	return o.url, nil
+}
+
+// accountDetails returns the email, secret key and url used to add an
+// account, reading any given by WithCredentialsFromFiles
+func (o *Op) accountDetails() (email, secretKey, url string, err error) {
+	email, secretKey, url = o.email, o.secretKey, o.url
+	for _, f := range []struct {
+		path  string
+		value *string
+	}{
+		{o.credFiles.Email, &email},
+		{o.credFiles.SecretKey, &secretKey},
+		{o.credFiles.URL, &url},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if *f.value, err = readSecretFile(f.path); err != nil {
+			return "", "", "", err
+		}
+	}
+	return email, secretKey, url, nil
+}
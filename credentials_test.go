@@ -0,0 +1,130 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPasswordFile(t *testing.T) {
+	configImpl = mockConfiger{}
+	dir := t.TempDir()
+	tests := []struct {
+		name    string
+		content string
+		perm    os.FileMode
+		wantErr string
+	}{
+		{"Valid", "filepass\n", 0400, ""},
+		{"WorldReadable", "filepass\n", 0604, "readable by other users"},
+		{"GroupReadable", "filepass\n", 0640, "readable by other users"},
+		{"Missing", "", 0, "unable to read"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if tt.perm != 0 {
+				if err := ioutil.WriteFile(path, []byte(tt.content), tt.perm); err != nil {
+					t.Fatal(err)
+				}
+			}
+			stdinFile := filepath.Join(dir, tt.name+"-stdin")
+			_, err := New(WithPasswordFile(path), WithRunner(captureCmd(stdinFile)))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Expected error containing %s, got: %v\n", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			got, err := ioutil.ReadFile(stdinFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != "filepass" {
+				t.Fatalf("Got password: %q, want: filepass\n", got)
+			}
+		})
+	}
+}
+
+func TestCredentialsFromFiles(t *testing.T) {
+	// containers don't have an op config
+	configImpl = configer{path: filepath.Join(t.TempDir(), "config")}
+	dir := t.TempDir()
+	files := CredFiles{}
+	for _, f := range []struct {
+		name    string
+		content string
+		path    *string
+	}{
+		{"password", "filepass\n", &files.Password},
+		{"secret-key", "A3-SECRET\n", &files.SecretKey},
+		{"url", "my_team.1password.com\n", &files.URL},
+		{"email", "user@bar.com\n", &files.Email},
+	} {
+		*f.path = filepath.Join(dir, f.name)
+		if err := ioutil.WriteFile(*f.path, []byte(f.content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name     string
+		runner   func(ctx context.Context, name string, args ...string) *exec.Cmd
+		wantArgs string
+	}{
+		{"V1", mockCmd, "[signin my_team.1password.com user@bar.com A3-SECRET]"},
+		{"V2", mockCmdV2, "[account add --address my_team.1password.com --email user@bar.com --secret-key A3-SECRET --signin]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdinFile := filepath.Join(dir, tt.name+"-stdin")
+			var got []string
+			recordCmd := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				cmd := tt.runner(ctx, name, args...)
+				if args[0] == "signin" || args[0] == "account" {
+					got = args
+					cmd.Env = append(cmd.Env, "OP_MOCK_CAPTURE="+stdinFile)
+				}
+				return cmd
+			}
+			o, err := New(WithCredentialsFromFiles(files), WithRunner(recordCmd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.Account() != "my_team.1password.com" {
+				t.Fatalf("Got account: %s, want: my_team.1password.com\n", o.Account())
+			}
+			if fmt.Sprint(got) != tt.wantArgs {
+				t.Fatalf("Got args: %v, want: %s\n", got, tt.wantArgs)
+			}
+			password, err := ioutil.ReadFile(stdinFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(password) != "filepass" {
+				t.Fatalf("Got password: %q, want: filepass\n", password)
+			}
+		})
+	}
+
+	if err := os.Chmod(files.SecretKey, 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := New(WithCredentialsFromFiles(files), WithRunner(mockCmd))
+	if err == nil || !strings.Contains(err.Error(), "readable by other users") {
+		t.Fatalf("Expected a readable by other users error, got: %v\n", err)
+	}
+
+	_, err = New(WithServiceAccountToken("ops_token"), WithCredentialsFromFiles(CredFiles{URL: files.URL}), WithRunner(mockCmdV2))
+	if err == nil || !strings.Contains(err.Error(), "service account token") {
+		t.Fatalf("Expected a service account token error, got: %v\n", err)
+	}
+}
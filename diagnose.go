@@ -0,0 +1,73 @@
+package op
+
+import (
+	"context"
+	"fmt"
+)
+
+// Check is the result of one of the checks made by Diagnose
+type Check struct {
+	Name string
+	// Err is nil if the check passed
+	Err error
+}
+
+// DiagnosisReport holds the checks made by Diagnose in the order they were
+// made. Checks stop at the first failure as the rest depend on it.
+type DiagnosisReport struct {
+	Checks []Check
+}
+
+// OK reports whether every check passed
+func (r DiagnosisReport) OK() bool {
+	return r.Err() == nil
+}
+
+// Err returns the error of the check which failed, or nil if they all passed
+func (r DiagnosisReport) Err() error {
+	for _, c := range r.Checks {
+		if c.Err != nil {
+			return fmt.Errorf("%s check failed: %w", c.Name, c.Err)
+		}
+	}
+	return nil
+}
+
+// Diagnose checks that an Op could be created with opts and used, such as
+// for a readiness probe, without reading any secrets. It checks that:
+//   - binary: the op binary is installed
+//   - version: op reports a version that can be parsed
+//   - config: the account is given or can be read from the op config
+//   - session: a session can be established and is accepted by op
+//
+// The returned error is that of the first check to fail, if any.
+func Diagnose(opts ...Opt) (DiagnosisReport, error) {
+	ctx := context.Background()
+	o := newOp(opts...)
+	checks := []struct {
+		name string
+		run  func() error
+	}{
+		{"binary", o.findBinary},
+		{"version", func() error {
+			_, err := o.isV2(ctx)
+			return err
+		}},
+		{"config", o.findAccount},
+		{"session", func() error {
+			if err := o.getEnv(ctx); err != nil {
+				return err
+			}
+			return o.checkSession(ctx)
+		}},
+	}
+	var r DiagnosisReport
+	for _, c := range checks {
+		err := c.run()
+		r.Checks = append(r.Checks, Check{Name: c.name, Err: err})
+		if err != nil {
+			break
+		}
+	}
+	return r, r.Err()
+}
@@ -0,0 +1,49 @@
+package op
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiagnose(t *testing.T) {
+	missing := configer{path: filepath.Join(t.TempDir(), "config")}
+	tests := []struct {
+		name    string
+		opts    []Opt
+		want    string
+		ok      bool
+		wantErr error
+	}{
+		{"OK", []Opt{WithRunner(mockCmd)}, "[binary version config session]", true, nil},
+		{"NoBinary", []Opt{WithBinaryPath(filepath.Join(t.TempDir(), "op"))}, "[binary]", false, ErrOpNotInstalled},
+		{"NoConfig", []Opt{WithConfigReader(missing), WithRunner(mockCmd)}, "[binary version config]", false, nil},
+		{"BadPassword", []Opt{WithAccount("badpass"), WithPassword("greatpass"), WithRunner(mockCmd)}, "[binary version config session]", false, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configImpl = mockConfiger{}
+			r, err := Diagnose(tt.opts...)
+			var names []string
+			for _, c := range r.Checks {
+				names = append(names, c.Name)
+			}
+			if got := fmt.Sprint(names); got != tt.want {
+				t.Fatalf("Got checks: %s, want: %s\n", got, tt.want)
+			}
+			if tt.ok {
+				if err != nil || !r.OK() {
+					t.Fatal("Unexpected error:", err)
+				}
+				return
+			}
+			if err == nil || r.OK() || r.Checks[len(r.Checks)-1].Err == nil {
+				t.Fatalf("Expected the final check to fail, got: %v\n", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Got error: %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maxSuggestions is the number of similar titles included in an error
+const maxSuggestions = 5
+
+// suggest adds the titles of items similar to item to err. err is returned
+// unchanged if there are none or the items can't be listed.
+func (o *Op) suggest(ctx context.Context, item string, err error) error {
+	items, lerr := o.listItems(ctx)
+	if lerr != nil {
+		return err
+	}
+	var similar []string
+	for _, i := range items {
+		if len(similar) == maxSuggestions {
+			break
+		}
+		if isSimilar(item, i.Title) {
+			similar = append(similar, fmt.Sprintf("'%s'", i.Title))
+		}
+	}
+	if len(similar) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w; did you mean %s?", err, strings.Join(similar, ", "))
+}
+
+// isSimilar reports whether title contains name, ignoring case, or is within
+// a few edits of it
+func isSimilar(name, title string) bool {
+	name, title = strings.ToLower(name), strings.ToLower(title)
+	if strings.Contains(title, name) || strings.Contains(name, title) {
+		return true
+	}
+	// allow one edit for every four characters
+	return levenshtein(name, title) <= (len(name)+3)/4
+}
+
+// levenshtein returns the number of single character insertions, deletions
+// and substitutions needed to change a into b
+func levenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	prev := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(s); i++ {
+		cur := make([]int, len(t)+1)
+		cur[0] = i
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(t)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
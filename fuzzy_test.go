@@ -0,0 +1,62 @@
+package op
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"foobar", "foobar", 0},
+		{"foobar", "fobar", 1},
+		{"foobar", "foobaz", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+	}
+	for _, tt := range tests {
+		if got := levenshtein(tt.a, tt.b); got != tt.want {
+			t.Fatalf("levenshtein(%q, %q) = %d, want: %d\n", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestFuzzyMatch(t *testing.T) {
+	configImpl = mockConfiger{}
+	tests := []struct {
+		name     string
+		opts     []Opt
+		item     string
+		wantHint string
+	}{
+		{"Typo", []Opt{WithFuzzyMatch()}, "fobar", "did you mean 'FOOBAR'?"},
+		{"Substring", []Opt{WithFuzzyMatch()}, "foo", "did you mean 'FOOBAR'?"},
+		{"NoMatch", []Opt{WithFuzzyMatch()}, "unrelated", ""},
+		{"Disabled", nil, "fobar", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(append(tt.opts, WithRunner(mockCmd))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = o.GetUserPass(tt.item)
+			if !errors.Is(err, ErrItemNotFound) {
+				t.Fatalf("Got error: %v, want: %v\n", err, ErrItemNotFound)
+			}
+			if tt.wantHint == "" {
+				if strings.Contains(err.Error(), "did you mean") {
+					t.Fatalf("Expected no suggestions, got: %v\n", err)
+				}
+				return
+			}
+			if !strings.HasSuffix(err.Error(), tt.wantHint) {
+				t.Fatalf("Got error: %v, want it to end with: %s\n", err, tt.wantHint)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package op
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultPasswordLength = 32
+
+// PasswordRecipe controls the passwords produced by GeneratePassword. The zero
+// value produces a 32 character password containing letters, digits and
+// symbols.
+type PasswordRecipe struct {
+	Length  int
+	Letters bool
+	Digits  bool
+	Symbols bool
+}
+
+// String returns the recipe in the format expected by --generate-password
+func (r PasswordRecipe) String() string {
+	var parts []string
+	if r.Letters {
+		parts = append(parts, "letters")
+	}
+	if r.Digits {
+		parts = append(parts, "digits")
+	}
+	if r.Symbols {
+		parts = append(parts, "symbols")
+	}
+	if len(parts) == 0 {
+		parts = []string{"letters", "digits", "symbols"}
+	}
+	length := r.Length
+	if length <= 0 {
+		length = defaultPasswordLength
+	}
+	return strings.Join(append(parts, strconv.Itoa(length)), ",")
+}
+
+// GeneratePassword returns a password produced by op's password generator.
+// op can only generate passwords while creating an item, so a temporary
+// Password item is created and then deleted. For that reason an error is
+// returned if WithDryRun has been used.
+func (o *Op) GeneratePassword(recipe PasswordRecipe) (pass string, err error) {
+	if o.dryRun != nil {
+		return "", fmt.Errorf("unable to generate a password in dry run mode: op only generates passwords when creating an item")
+	}
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return "", err
+	}
+	title := fmt.Sprintf("op-generated-%d", time.Now().UnixNano())
+	generate := "--generate-password=" + recipe.String()
+	args := []string{"create", "item", "Password", generate, "--title", title}
+	if v2 {
+		args = []string{"item", "create", "--category", "Password", generate, "--title", title, "--format", "json"}
+	}
+	out, err := o.RunOpContext(ctx, o.withVault(args...)...)
+	if err != nil {
+		return "", err
+	}
+
+	// the item exists from here on so it is always deleted, by title if its
+	// UUID can't be found
+	uuid := title
+	defer func() {
+		derr := o.delete("item", uuid)
+		switch {
+		case derr == nil:
+		case err == nil:
+			pass, err = "", fmt.Errorf("unable to delete temporary item %s: %v", uuid, derr)
+		default:
+			err = fmt.Errorf("%w (unable to delete temporary item %s: %v)", err, uuid, derr)
+		}
+	}()
+	if v2 {
+		var i opItemV2
+		if err := json.Unmarshal(out, &i); err != nil {
+			return "", fmt.Errorf("unable to unmarshal item data: %v", err)
+		}
+		if i.ID != "" {
+			uuid = i.ID
+		}
+		_, pass = i.toV1().userPass()
+	} else {
+		id, err := parseUUID(out)
+		if err != nil {
+			return "", err
+		}
+		if id != "" {
+			uuid = id
+		}
+		i, err := o.getItem(ctx, uuid)
+		if err != nil {
+			return "", err
+		}
+		pass = i.Details.Password
+	}
+	if pass == "" {
+		return "", fmt.Errorf("op did not generate a password")
+	}
+	return pass, nil
+}
@@ -0,0 +1,94 @@
+package op
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestPasswordRecipe(t *testing.T) {
+	tests := []struct {
+		name   string
+		recipe PasswordRecipe
+		want   string
+	}{
+		{"Zero", PasswordRecipe{}, "letters,digits,symbols,32"},
+		{"DigitsOnly", PasswordRecipe{Length: 6, Digits: true}, "digits,6"},
+		{"DefaultLength", PasswordRecipe{Letters: true, Symbols: true}, "letters,symbols,32"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.recipe.String(); got != tt.want {
+				t.Fatalf("Got: %s, want: %s\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	configImpl = mockConfiger{}
+	tests := []struct {
+		name        string
+		runner      func(ctx context.Context, name string, args ...string) *exec.Cmd
+		want        string
+		wantErr     string
+		wantCleanup string
+	}{
+		{"V1", mockCmd, "[create get delete]", "unable to delete temporary item undeletable", "delete item undeletable"},
+		{"V2", mockCmdV2, "[item item]", "unable to unmarshal item data", "item delete op-generated-"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			var last string
+			recordCmd := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				got = append(got, args[0])
+				last = strings.Join(args, " ")
+				return tt.runner(ctx, name, args...)
+			}
+			o, err := New(WithRunner(recordCmd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = nil
+			pass, err := o.GeneratePassword(PasswordRecipe{})
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if pass != "Gen3rated!" {
+				t.Fatalf("Got: %s, want: Gen3rated!\n", pass)
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Fatalf("Got commands: %v, want: %s\n", got, tt.want)
+			}
+
+			// the temporary item is deleted even if the password can't be read
+			got = nil
+			o, err = New(WithVault("Broken"), WithRunner(recordCmd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := o.GeneratePassword(PasswordRecipe{}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Got error: %v, want it to contain: %s\n", err, tt.wantErr)
+			}
+			if !strings.HasPrefix(last, tt.wantCleanup) {
+				t.Fatalf("Got last command: %s, want it to start with: %s\n", last, tt.wantCleanup)
+			}
+
+			var buf bytes.Buffer
+			o, err = New(WithDryRun(&buf), WithRunner(tt.runner))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := o.GeneratePassword(PasswordRecipe{}); err == nil || !strings.Contains(err.Error(), "dry run") {
+				t.Fatalf("Expected a dry run error, got: %v\n", err)
+			}
+			if buf.Len() != 0 {
+				t.Fatalf("Got dry run output: %s\n", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package op
+
+import (
+	"context"
+	"time"
+)
+
+// Item holds the details of an item including fields grouped into sections
+type Item struct {
+	UUID  string
+	Title string
+	URL   string
+	Tags  []string
+	Notes string
+	// Fields holds the fields which don't belong to a section, such as the
+	// username and password of a login
+	Fields   []Field
+	Sections []Section
+}
+
+// Section is a titled group of fields within an item
+type Section struct {
+	ID     string
+	Title  string
+	Fields []Field
+}
+
+// Field is a single field of an item. Type is as reported by op and so
+// depends on the version in use.
+type Field struct {
+	ID    string
+	Label string
+	Type  string
+	Value string
+}
+
+// GetItemDetailed returns the details of an item, keeping fields which belong
+// to a section grouped by that section
+func (o *Op) GetItemDetailed(item string) (Item, error) {
+	i, err := o.getItem(context.Background(), item)
+	if err != nil {
+		return Item{}, err
+	}
+	d := Item{
+		UUID:  i.UUID,
+		Title: i.Title,
+		URL:   i.Overview.URL,
+		Tags:  i.Overview.Tags,
+		Notes: i.Details.NotesPlain,
+	}
+	for _, f := range i.Details.Fields {
+		d.Fields = append(d.Fields, Field{ID: f.Name, Label: f.Name, Type: f.Type, Value: f.Value})
+	}
+	for _, s := range i.Details.Sections {
+		section := Section{ID: s.Name, Title: s.Title}
+		for _, f := range s.Fields {
+			section.Fields = append(section.Fields, Field{ID: f.N, Label: f.T, Type: f.K, Value: f.V})
+		}
+		d.Sections = append(d.Sections, section)
+	}
+	return d, nil
+}
+
+// GetItemTimes returns when an item was created and last updated
+func (o *Op) GetItemTimes(item string) (created, updated time.Time, err error) {
+	i, err := o.getItem(context.Background(), item)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return i.CreatedAt, i.UpdatedAt, nil
+}
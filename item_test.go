@@ -0,0 +1,64 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestGetItemDetailed(t *testing.T) {
+	configImpl = mockConfiger{}
+	tests := []struct {
+		name   string
+		runner func(ctx context.Context, name string, args ...string) *exec.Cmd
+		want   string
+	}{
+		{"V1", mockCmd, "{randogoo FOOBAR https://foo.com [app-managed]  [{username username T user@bar.com} {password password P greatpass}] [{linked items Related Items []} {Section_3  [{TOTP_foo one-time password concealed otpauth://totp}]}]}"},
+		{"V2", mockCmdV2, "{randogoo FOOBAR https://foo.com [app-managed]  [{username username string user@bar.com} {password password concealed greatpass}] [{Section_3  [{TOTP_foo one-time password otp otpauth://totp}]}]}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(WithRunner(tt.runner))
+			if err != nil {
+				t.Fatal(err)
+			}
+			i, err := o.GetItemDetailed("FOOBAR")
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if got := fmt.Sprint(i); got != tt.want {
+				t.Fatalf("Got: %s\nwant: %s\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetItemTimes(t *testing.T) {
+	configImpl = mockConfiger{}
+	tests := []struct {
+		name   string
+		runner func(ctx context.Context, name string, args ...string) *exec.Cmd
+	}{
+		{"V1", mockCmd},
+		{"V2", mockCmdV2},
+	}
+	wantCreated := time.Date(2019, 4, 9, 13, 20, 52, 0, time.UTC)
+	wantUpdated := time.Date(2019, 4, 17, 0, 48, 26, 0, time.UTC)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(WithRunner(tt.runner))
+			if err != nil {
+				t.Fatal(err)
+			}
+			created, updated, err := o.GetItemTimes("FOOBAR")
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if !created.Equal(wantCreated) || !updated.Equal(wantUpdated) {
+				t.Fatalf("Got: %v, %v, want: %v, %v\n", created, updated, wantCreated, wantUpdated)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package op
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// ItemSummary describes an item returned by ListItems
+type ItemSummary struct {
+	UUID  string
+	Title string
+	Vault string
+}
+
+type opListItem struct {
+	UUID      string `json:"uuid"`
+	VaultUUID string `json:"vaultUuid"`
+	Overview  struct {
+		Title string `json:"title"`
+	} `json:"overview"`
+}
+
+type opListItemV2 struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Vault struct {
+		ID string `json:"id"`
+	} `json:"vault"`
+}
+
+// ListItems returns a summary of every item in the account, or only those in
+// the vault given to WithVault
+func (o *Op) ListItems() ([]ItemSummary, error) {
+	return o.listItems(context.Background())
+}
+
+// listItems lists items, passing any filter flags such as --tags to op
+func (o *Op) listItems(ctx context.Context, filter ...string) ([]ItemSummary, error) {
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]string{"list", "items"}, filter...)
+	if v2 {
+		args = append([]string{"item", "list"}, filter...)
+	}
+	out, err := o.RunOpContext(ctx, withFormat(o.withVault(args...))...)
+	if err != nil {
+		return nil, err
+	}
+	items := []ItemSummary{}
+	if len(out) == 0 {
+		return items, nil
+	}
+	if v2 {
+		var list []opListItemV2
+		if err := json.Unmarshal(out, &list); err != nil {
+			return nil, fmt.Errorf("unable to unmarshal item list: %v", err)
+		}
+		for _, i := range list {
+			items = append(items, ItemSummary{UUID: i.ID, Title: i.Title, Vault: i.Vault.ID})
+		}
+		return items, nil
+	}
+	var list []opListItem
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal item list: %v", err)
+	}
+	for _, i := range list {
+		items = append(items, ItemSummary{UUID: i.UUID, Title: i.Overview.Title, Vault: i.VaultUUID})
+	}
+	return items, nil
+}
+
+// Vault describes a vault returned by ListVaults
+type Vault struct {
+	UUID string
+	Name string
+}
+
+type opVault struct {
+	UUID string `json:"uuid"`
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// ListVaults returns every vault accessible to the account
+func (o *Op) ListVaults() ([]Vault, error) {
+	v2, err := o.isV2(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"list", "vaults"}
+	if v2 {
+		args = []string{"vault", "list"}
+	}
+	out, err := o.RunOp(withFormat(args)...)
+	if err != nil {
+		return nil, err
+	}
+	vaults := []Vault{}
+	if len(out) == 0 {
+		return vaults, nil
+	}
+	var list []opVault
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal vault list: %v", err)
+	}
+	for _, v := range list {
+		// v1 identifies vaults by uuid, v2 by id
+		uuid := v.UUID
+		if uuid == "" {
+			uuid = v.ID
+		}
+		vaults = append(vaults, Vault{UUID: uuid, Name: v.Name})
+	}
+	return vaults, nil
+}
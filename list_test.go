@@ -0,0 +1,67 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"testing"
+)
+
+func TestListItems(t *testing.T) {
+	configImpl = mockConfiger{}
+	tests := []struct {
+		name   string
+		runner func(ctx context.Context, name string, args ...string) *exec.Cmd
+		opts   []Opt
+		want   string
+	}{
+		{"V1", mockCmd, nil, "[{randogoo FOOBAR rando1} {noteuuid NOTE rando1}]"},
+		{"V2", mockCmdV2, nil, "[{randogoo FOOBAR rando1} {noteuuid NOTE rando1}]"},
+		{"EmptyVault", mockCmd, []Opt{WithVault("Empty")}, "[]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(append(tt.opts, WithRunner(tt.runner))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			items, err := o.ListItems()
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if items == nil {
+				t.Fatal("Expected a non-nil slice")
+			}
+			if got := fmt.Sprint(items); got != tt.want {
+				t.Fatalf("Got: %s, want: %s\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListVaults(t *testing.T) {
+	configImpl = mockConfiger{}
+	tests := []struct {
+		name   string
+		runner func(ctx context.Context, name string, args ...string) *exec.Cmd
+	}{
+		{"V1", mockCmd},
+		{"V2", mockCmdV2},
+	}
+	want := "[{rando1 Private} {rando2 Shared}]"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(WithRunner(tt.runner))
+			if err != nil {
+				t.Fatal(err)
+			}
+			vaults, err := o.ListVaults()
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if got := fmt.Sprint(vaults); got != want {
+				t.Fatalf("Got: %s, want: %s\n", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// defaultConcurrency is the number of items fetched at once unless
+// WithConcurrency is used
+const defaultConcurrency = 4
+
+// Login holds the commonly used details of a login item
+type Login struct {
+	Username string
+	Password string
+	TOTP     string
+	URL      string
+	Fields   map[string]string
+}
+
+// GetLogin returns the details of a login item. The item is fetched with a
+// single call to op, although v1 of op requires a second call to retrieve the
+// TOTP if the item has one.
+func (o *Op) GetLogin(item string) (Login, error) {
+	return o.getLogin(context.Background(), item)
+}
+
+func (o *Op) getLogin(ctx context.Context, item string) (Login, error) {
+	i, err := o.getItem(ctx, item)
+	if err != nil {
+		return Login{}, err
+	}
+	l := Login{
+		TOTP:   i.totp,
+		URL:    i.Overview.URL,
+		Fields: i.fields(),
+	}
+	l.Username, l.Password = i.userPass()
+	if l.TOTP == "" && i.hasTotp() {
+		l.TOTP, err = o.GetTotpContext(ctx, item)
+		if err != nil {
+			return Login{}, err
+		}
+	}
+	return l, nil
+}
+
+// GetItemsByTag returns the details of every login item with the given tag, in
+// the order they are listed by op. Items are fetched in parallel. If any item
+// cannot be fetched, an error describing every failure is returned.
+func (o *Op) GetItemsByTag(tag string) ([]Login, error) {
+	ctx := context.Background()
+	items, err := o.listItems(ctx, "--tags", tag)
+	if err != nil {
+		return nil, err
+	}
+	return o.getLogins(ctx, items, fmt.Sprintf("tagged '%s'", tag))
+}
+
+// GetItemsMatching returns the details of every login item with a title
+// matching pattern, in the order they are listed by op. The pattern uses the
+// syntax of path.Match, so "svc-*" matches every title starting with "svc-",
+// but as titles aren't paths, wildcards also match /. Items are fetched in
+// parallel and an empty slice is returned if no titles match.
+func (o *Op) GetItemsMatching(pattern string) ([]Login, error) {
+	if _, err := matchTitle(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+	ctx := context.Background()
+	items, err := o.listItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var matched []ItemSummary
+	for _, i := range items {
+		if ok, _ := matchTitle(pattern, i.Title); ok {
+			matched = append(matched, i)
+		}
+	}
+	return o.getLogins(ctx, matched, fmt.Sprintf("matching '%s'", pattern))
+}
+
+// flatTitle replaces the / which path.Match never matches with a wildcard by a
+// character which isn't found in titles
+var flatTitle = strings.NewReplacer("/", "\uffff")
+
+// matchTitle reports whether title matches pattern like path.Match, except
+// that wildcards also match /
+func matchTitle(pattern, title string) (bool, error) {
+	return path.Match(flatTitle.Replace(pattern), flatTitle.Replace(title))
+}
+
+// getLogins fetches the details of items in parallel. If any item cannot be
+// fetched, an error describing every failure is returned.
+func (o *Op) getLogins(ctx context.Context, items []ItemSummary, desc string) ([]Login, error) {
+	logins := make([]Login, len(items))
+	errs := make([]error, len(items))
+	sem := o.semaphore()
+	var wg sync.WaitGroup
+	for n := range items {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// fetch by uuid as titles may not be unique
+			logins[n], errs[n] = o.getLogin(ctx, items[n].UUID)
+		}(n)
+	}
+	wg.Wait()
+
+	var first error
+	var failed []string
+	for n, err := range errs {
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			failed = append(failed, fmt.Sprintf("'%s': %v", items[n].Title, err))
+		}
+	}
+	if first != nil {
+		return nil, fmt.Errorf("unable to fetch %d of %d items %s: %w (%s)", len(failed), len(items), desc, first, strings.Join(failed, "; "))
+	}
+	return logins, nil
+}
+
+// GetManyOpt changes how GetMany behaves
+type GetManyOpt func(*getManyOptions)
+
+type getManyOptions struct {
+	failFast bool
+}
+
+// FailFast makes GetMany stop fetching items and return only the first error
+// as soon as any item cannot be fetched
+func FailFast() GetManyOpt {
+	return func(g *getManyOptions) {
+		g.failFast = true
+	}
+}
+
+// GetManyError is returned by GetMany when some of the items couldn't be
+// fetched. It holds the error for each of them, keyed by the requested name.
+type GetManyError map[string]error
+
+func (e GetManyError) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	failed := make([]string, len(names))
+	for n, name := range names {
+		failed[n] = fmt.Sprintf("'%s': %v", name, e[name])
+	}
+	return fmt.Sprintf("unable to fetch %d items: %s", len(e), strings.Join(failed, "; "))
+}
+
+// Is reports whether the error for any of the items matches target, so that
+// errors.Is can be used to check for ErrItemNotFound
+func (e GetManyError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMany returns the details of each of the login items, keyed by the
+// requested name. Items are fetched in parallel. If any can't be fetched, the
+// items which could are returned along with a GetManyError holding the error
+// for each of the others, unless FailFast is used.
+func (o *Op) GetMany(items []string, opts ...GetManyOpt) (map[string]Login, error) {
+	var g getManyOptions
+	for _, opt := range opts {
+		opt(&g)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logins := make(map[string]Login, len(items))
+	errs := GetManyError{}
+	var first error
+	var mu sync.Mutex
+	sem := o.semaphore()
+	var wg sync.WaitGroup
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		if seen[item] {
+			continue
+		}
+		seen[item] = true
+		wg.Add(1)
+		go func(item string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			// a failure has already been seen
+			if ctx.Err() != nil {
+				return
+			}
+			l, err := o.getLogin(ctx, item)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				logins[item] = l
+				return
+			}
+			errs[item] = err
+			if g.failFast && first == nil {
+				first = fmt.Errorf("unable to fetch '%s': %w", item, err)
+				cancel()
+			}
+		}(item)
+	}
+	wg.Wait()
+
+	if first != nil {
+		return nil, first
+	}
+	if len(errs) > 0 {
+		return logins, errs
+	}
+	return logins, nil
+}
+
+// semaphore returns a channel which limits the number of concurrent fetches
+// to the value given to WithConcurrency
+func (o *Op) semaphore() chan struct{} {
+	n := o.concurrency
+	if n < 1 {
+		n = defaultConcurrency
+	}
+	return make(chan struct{}, n)
+}
+
+// LoginSpec describes a login item to be created by CreateLogin
+type LoginSpec struct {
+	Title    string
+	Username string
+	Password string
+	URL      string
+	Notes    string
+	// Fields are added to the item as text fields in a section of their own
+	Fields map[string]string
+	// Tags and Vault take the place of any set with WithTags and WithVault
+	Tags  []string
+	Vault string
+}
+
+// CreateLogin creates a login item from spec. Unlike SetUserPass, an existing
+// item with the same title is left in place.
+func (o *Op) CreateLogin(spec LoginSpec) error {
+	if err := validateItem(spec.Title, "Login"); err != nil {
+		return err
+	}
+	detail := opDetails{
+		Fields: []opField{
+			{Designation: "username", Name: "username", Type: "T", Value: spec.Username},
+			{Designation: "password", Name: "password", Type: "P", Value: spec.Password},
+		},
+		NotesPlain: spec.Notes,
+	}
+	if len(spec.Fields) > 0 {
+		names := make([]string, 0, len(spec.Fields))
+		for name := range spec.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		section := opSection{Name: "Section_fields"}
+		for _, name := range names {
+			section.Fields = append(section.Fields, opSectionField{K: "string", N: name, T: name, V: spec.Fields[name]})
+		}
+		detail.Sections = []opSection{section}
+	}
+	args := []string{"--title", spec.Title}
+	if spec.URL != "" {
+		args = append(args, "--url", spec.URL)
+	}
+	tags := o.tags
+	if len(spec.Tags) > 0 {
+		tags = spec.Tags
+	}
+	if len(tags) > 0 {
+		args = append(args, "--tags", strings.Join(tags, ","))
+	}
+	vault := o.vault
+	if spec.Vault != "" {
+		vault = spec.Vault
+	}
+	if vault != "" {
+		args = append(args, "--vault", vault)
+	}
+	_, err := o.create("item", "Login", detail, args...)
+	return err
+}
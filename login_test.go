@@ -0,0 +1,282 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGetLogin(t *testing.T) {
+	configImpl = mockConfiger{}
+	tests := []struct {
+		name      string
+		runner    func(ctx context.Context, name string, args ...string) *exec.Cmd
+		item      string
+		want      string
+		wantCalls int
+	}{
+		{"V1", mockCmd, "FOOBAR", "{user@bar.com greatpass 123456 https://foo.com map[password:greatpass username:user@bar.com]}", 2},
+		{"V2", mockCmdV2, "FOOBAR", "{user@bar.com greatpass 123456 https://foo.com map[password:greatpass username:user@bar.com]}", 1},
+		{"NoTotp", mockCmd, "NOTE", "{    map[]}", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			countCmd := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				calls++
+				return tt.runner(ctx, name, args...)
+			}
+			o, err := New(WithRunner(countCmd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			calls = 0
+			l, err := o.GetLogin(tt.item)
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if got := fmt.Sprint(l); got != tt.want {
+				t.Fatalf("Got: %s, want: %s\n", got, tt.want)
+			}
+			if calls != tt.wantCalls {
+				t.Fatalf("Expected %d calls to op, got %d\n", tt.wantCalls, calls)
+			}
+		})
+	}
+}
+
+func TestGetItemsByTag(t *testing.T) {
+	configImpl = mockConfiger{}
+	tests := []struct {
+		name    string
+		runner  func(ctx context.Context, name string, args ...string) *exec.Cmd
+		tag     string
+		want    string
+		wantErr error
+	}{
+		{"V1", mockCmd, "app-managed", "[{user@bar.com greatpass 123456 https://foo.com map[password:greatpass username:user@bar.com]}]", nil},
+		{"V2", mockCmdV2, "app-managed", "[{user@bar.com greatpass 123456 https://foo.com map[password:greatpass username:user@bar.com]}]", nil},
+		{"NoItems", mockCmd, "none", "[]", nil},
+		{"Partial", mockCmdV2, "partial", "", ErrItemNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(WithRunner(tt.runner))
+			if err != nil {
+				t.Fatal(err)
+			}
+			logins, err := o.GetItemsByTag(tt.tag)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Got error: %v, want: %v\n", err, tt.wantErr)
+				}
+				if !strings.Contains(err.Error(), "1 of 2 items") || !strings.Contains(err.Error(), "'GONE'") {
+					t.Fatalf("Expected the failed item in the error, got: %v\n", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if got := fmt.Sprint(logins); got != tt.want {
+				t.Fatalf("Got: %s, want: %s\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetItemsMatching(t *testing.T) {
+	configImpl = mockConfiger{}
+	tests := []struct {
+		name    string
+		runner  func(ctx context.Context, name string, args ...string) *exec.Cmd
+		pattern string
+		want    string
+		wantErr error
+	}{
+		{"V1", mockCmd, "FOO*", "[{user@bar.com greatpass 123456 https://foo.com map[password:greatpass username:user@bar.com]}]", nil},
+		{"V2", mockCmdV2, "FOO*", "[{user@bar.com greatpass 123456 https://foo.com map[password:greatpass username:user@bar.com]}]", nil},
+		{"NoMatch", mockCmdV2, "svc-*", "[]", nil},
+		{"BadPattern", mockCmdV2, "[", "", path.ErrBadPattern},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(WithRunner(tt.runner))
+			if err != nil {
+				t.Fatal(err)
+			}
+			logins, err := o.GetItemsMatching(tt.pattern)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Got error: %v, want: %v\n", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if got := fmt.Sprint(logins); got != tt.want {
+				t.Fatalf("Got: %s, want: %s\n", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchTitle(t *testing.T) {
+	tests := []struct {
+		pattern string
+		title   string
+		want    bool
+	}{
+		{"svc-*", "svc-a", true},
+		{"svc-*", "svc-a/b", true},
+		{"svc-?/b", "svc-a/b", true},
+		{"svc-a?b", "svc-a/b", true},
+		{"svc-a/b", "svc-a/b", true},
+		{"svc-[^a]", "svc-/", true},
+		{"svc-*", "app-svc", false},
+	}
+	for _, tt := range tests {
+		got, err := matchTitle(tt.pattern, tt.title)
+		if err != nil {
+			t.Fatal("Unexpected error:", err)
+		}
+		if got != tt.want {
+			t.Errorf("matchTitle(%s, %s) = %v, want: %v\n", tt.pattern, tt.title, got, tt.want)
+		}
+	}
+}
+
+func TestConcurrency(t *testing.T) {
+	configImpl = mockConfiger{}
+	for _, n := range []int{1, 2} {
+		t.Run(fmt.Sprint(n), func(t *testing.T) {
+			var mu sync.Mutex
+			var active, max int
+			slowCmd := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				if args[0] == "item" && args[1] == "get" {
+					mu.Lock()
+					active++
+					if active > max {
+						max = active
+					}
+					mu.Unlock()
+					time.Sleep(50 * time.Millisecond)
+					mu.Lock()
+					active--
+					mu.Unlock()
+				}
+				return mockCmdV2(ctx, name, args...)
+			}
+			o, err := New(WithConcurrency(n), WithRunner(slowCmd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the result doesn't matter, only that both items are fetched
+			o.GetItemsByTag("partial")
+			if max != n {
+				t.Fatalf("Got %d concurrent fetches, want: %d\n", max, n)
+			}
+		})
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	configImpl = mockConfiger{}
+	o, err := New(WithRunner(mockCmdV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "map[FOOBAR:{user@bar.com greatpass 123456 https://foo.com map[password:greatpass username:user@bar.com]}]"
+	logins, err := o.GetMany([]string{"FOOBAR", "FOOBAR"})
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if got := fmt.Sprint(logins); got != want {
+		t.Fatalf("Got: %s, want: %s\n", got, want)
+	}
+
+	// the items which could be fetched are still returned
+	logins, err = o.GetMany([]string{"FOOBAR", "missing", "gone"})
+	var manyErr GetManyError
+	if !errors.As(err, &manyErr) || len(manyErr) != 2 || !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("Got error: %v, want a GetManyError for 2 items\n", err)
+	}
+	if got := fmt.Sprint(logins); got != want {
+		t.Fatalf("Got: %s, want: %s\n", got, want)
+	}
+
+	logins, err = o.GetMany([]string{"missing", "FOOBAR"}, FailFast())
+	if !errors.Is(err, ErrItemNotFound) || !strings.Contains(err.Error(), "'missing'") {
+		t.Fatalf("Got error: %v, want: %v\n", err, ErrItemNotFound)
+	}
+	if logins != nil {
+		t.Fatalf("Got: %v, want no logins\n", logins)
+	}
+}
+
+func TestCreateLogin(t *testing.T) {
+	configImpl = mockConfiger{}
+	spec := LoginSpec{
+		Title:    "NEW",
+		Username: "user@bar.com",
+		Password: "greatpass",
+		URL:      "https://foo.com",
+		Notes:    "some notes",
+		Fields:   map[string]string{"region": "eu", "account": "1234"},
+		Tags:     []string{"app", "prod"},
+		Vault:    "Prod",
+	}
+	tests := []struct {
+		name   string
+		runner func(path string) func(ctx context.Context, name string, args ...string) *exec.Cmd
+		v2     bool
+		want   string
+	}{
+		{"V1", captureCmd, false, "[create item Login --title NEW --url https://foo.com --tags app,prod --vault Prod --template FILE]"},
+		{"V2", captureCmdV2, true, "[item create --category Login --format json --title NEW --url https://foo.com --tags app,prod --vault Prod --template FILE]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			stdinFile := filepath.Join(t.TempDir(), "stdin")
+			recordCmd := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				got = stripTemplate(args)
+				return tt.runner(stdinFile)(ctx, name, args...)
+			}
+			o, err := New(WithTags("default"), WithRunner(recordCmd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := o.CreateLogin(spec); err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if fmt.Sprint(got) != tt.want {
+				t.Fatalf("Got args: %v, want: %s\n", got, tt.want)
+			}
+			i := readTemplate(t, stdinFile, tt.v2)
+			if user, pass := i.userPass(); user != spec.Username || pass != spec.Password {
+				t.Fatalf("Got username: %s, password: %s\n", user, pass)
+			}
+			if i.Details.NotesPlain != spec.Notes {
+				t.Fatalf("Got notes: %s, want: %s\n", i.Details.NotesPlain, spec.Notes)
+			}
+			for name, value := range spec.Fields {
+				if got := i.field(name); got != value {
+					t.Fatalf("Got %s: %s, want: %s\n", name, got, value)
+				}
+			}
+
+			if err := o.CreateLogin(LoginSpec{Username: "user@bar.com"}); err == nil {
+				t.Fatal("Expected an error creating a login without a title")
+			}
+		})
+	}
+}
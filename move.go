@@ -0,0 +1,60 @@
+package op
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MoveItem moves an item to destVault. The item is looked up in the vault given
+// to WithVault, if any. v1 of op can't move items, so the item is copied to
+// destVault and then deleted, which gives it a new UUID and history. Documents
+// can only be moved with v2.
+func (o *Op) MoveItem(item, destVault string) error {
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return err
+	}
+	if v2 {
+		args := []string{"item", "move", item, "--destination-vault", destVault}
+		if o.vault != "" {
+			args = append(args, "--current-vault", o.vault)
+		}
+		_, err := o.RunOpContext(ctx, args...)
+		return err
+	}
+
+	var i struct {
+		UUID         string          `json:"uuid"`
+		TemplateUUID string          `json:"templateUuid"`
+		Details      json.RawMessage `json:"details"`
+		Overview     struct {
+			Title string   `json:"title"`
+			URL   string   `json:"url"`
+			Tags  []string `json:"tags"`
+		} `json:"overview"`
+	}
+	if err := o.get(ctx, "item", item, &i); err != nil {
+		return err
+	}
+	category, ok := categories[i.TemplateUUID]
+	if !ok {
+		return fmt.Errorf("unable to move '%s': items of template %s can't be moved with v1 of op", item, i.TemplateUUID)
+	}
+	// create the copy first so the item isn't lost if that fails
+	args := []string{"create", "item", category, "--title", i.Overview.Title, "--vault", destVault}
+	// the website is kept in the overview rather than the details
+	if i.Overview.URL != "" {
+		args = append(args, "--url", i.Overview.URL)
+	}
+	if len(i.Overview.Tags) > 0 {
+		args = append(args, "--tags", strings.Join(i.Overview.Tags, ","))
+	}
+	if _, err := o.runWithTemplate(ctx, i.Details, []string{string(i.Details)}, args...); err != nil {
+		return err
+	}
+	_, err = o.RunOpContext(ctx, o.withVault("delete", "item", i.UUID)...)
+	return err
+}
@@ -0,0 +1,61 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveItem(t *testing.T) {
+	configImpl = mockConfiger{}
+	capture := filepath.Join(t.TempDir(), "capture")
+	tests := []struct {
+		name     string
+		runner   func(ctx context.Context, name string, args ...string) *exec.Cmd
+		opts     []Opt
+		wantArgs string
+	}{
+		{"V1", captureCmd(capture), nil, "[[get item FOOBAR] [create item Login --title FOOBAR --vault Shared --url https://foo.com --tags app-managed --template FILE] [delete item randogoo]]"},
+		{"V2", mockCmdV2, []Opt{WithVault("Private")}, "[[item move FOOBAR --destination-vault Shared --current-vault Private]]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			recordCmd := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				got = append(got, stripTemplate(args))
+				return tt.runner(ctx, name, args...)
+			}
+			o, err := New(append(tt.opts, WithRunner(recordCmd))...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = nil
+			if _, err := o.Version(); err != nil {
+				t.Fatal(err)
+			}
+			if err := o.MoveItem("FOOBAR", "Shared"); err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if fmt.Sprint(got) != tt.wantArgs {
+				t.Fatalf("Got args: %v, want: %s\n", got, tt.wantArgs)
+			}
+			if err := o.MoveItem("missing", "Shared"); !errors.Is(err, ErrItemNotFound) {
+				t.Fatalf("Got error: %v, want: %v\n", err, ErrItemNotFound)
+			}
+		})
+	}
+
+	// the v1 copy keeps every detail of the original
+	payload, err := ioutil.ReadFile(capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"fields":[{"designation":"username","name":"username","type":"T","value":"user@bar.com"},{"designation":"password","name":"password","type":"P","value":"greatpass"}],"passwordHistory":[{"value":"olderpass","time":1550000000},{"value":"oldpass","time":1555000000}],"sections":[{"name":"linked items","title":"Related Items"},{"fields":[{"k":"concealed","n":"TOTP_foo","t":"one-time password","v":"otpauth://totp"}],"name":"Section_3"}]}`
+	if string(payload) != want {
+		t.Fatalf("Got details: %s, want: %s\n", payload, want)
+	}
+}
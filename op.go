@@ -1,80 +1,355 @@
 package op
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"log"
+	"math/rand"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
 
-	"github.com/dvsekhvalnov/jose2go/base64url"
 	"github.com/mitchellh/go-homedir"
 )
 
 const (
-	envPrefix  = "OP_SESSION_"
-	configFile = "~/.op/config"
-	newLine    = 0xa
+	defaultPrefix = "OP_SESSION_"
+	configFile    = "~/.op/config"
+	configFileV2  = "~/.config/op/config"
+	newLine       = 0xa
+	carriageRet   = 0xd
+	defaultBinary = "op"
+	serviceVar    = "OP_SERVICE_ACCOUNT_TOKEN"
+	// defaultMaxOutput is the most op may write to stdout or stderr unless
+	// WithMaxOutputSize is used
+	defaultMaxOutput = 10 << 20
 )
 
 var authRequired = regexp.MustCompile("(not currently|Authentication)")
-var doesNotExist = regexp.MustCompile("(doesn't seem to be an item|no item found|not found)")
+var doesNotExist = regexp.MustCompile("(doesn't seem to be an item|no item found|not found|isn't an item)")
+var deviceID = regexp.MustCompile("^(?i)[a-z2-7]{26}$")
+var rateLimited = regexp.MustCompile("(?i)(too many requests|rate limit|status 429)")
+var connectionFailed = regexp.MustCompile("(?i)(dial tcp|no such host|connection refused|connection reset|network is unreachable|i/o timeout|TLS handshake timeout|could not connect|could not reach)")
+
+// ErrItemNotFound is returned when op reports that an item does not exist
+var ErrItemNotFound = errors.New("item not found")
+
+// ErrFieldNotFound is returned when an item does not contain the requested field
+var ErrFieldNotFound = errors.New("field not found")
+
+// ErrConnectionFailed is returned when op is unable to reach 1Password. These
+// failures are usually transient so the operation may be retried.
+var ErrConnectionFailed = errors.New("connection failed")
+
+// ErrRateLimited is returned when 1Password has rejected a request because too
+// many have been made. The operation may be retried after a delay.
+var ErrRateLimited = errors.New("rate limited")
+
+// ErrSessionExpired is returned when op reports that the session is no longer
+// valid. A new Op should be created to sign-in again.
+var ErrSessionExpired = errors.New("session expired")
+
+// ErrOpNotInstalled is returned when the op binary cannot be found
+var ErrOpNotInstalled = errors.New("op is not installed")
+
+// ErrOutputTooLarge is returned when op writes more than the limit set by
+// WithMaxOutputSize
+var ErrOutputTooLarge = errors.New("op output too large")
+
+// ErrReadOnly is returned by methods which would change the account when
+// WithReadOnly has been used
+var ErrReadOnly = errors.New("op is read-only")
+
+// ExitError is returned when op exits with a non-zero status. It wraps the
+// error the failure was classified as, such as ErrItemNotFound, if any.
+type ExitError struct {
+	// Code is the exit status of op, or -1 if it was killed by a signal
+	Code int
+	// OpCode is the code op gave the error, such as "not_found", if it was
+	// reported as JSON. Codes which are numbers are formatted in decimal.
+	OpCode string
+	// Stderr is the output of op with any secrets redacted
+	Stderr string
+	err    error
+	// quiet leaves stderr out of the message when err describes it fully
+	quiet bool
+}
+
+func (e *ExitError) Error() string {
+	switch {
+	case e.err == nil:
+		return e.Stderr
+	case e.quiet:
+		return e.err.Error()
+	}
+	return fmt.Sprintf("%v: %s", e.err, e.Stderr)
+}
+
+// Unwrap returns the error the failure was classified as
+func (e *ExitError) Unwrap() error {
+	return e.err
+}
 
 type opConfig struct {
-	LatestSignIn *string `json:"latest_signin,omitempty"`
-	Accounts     []struct {
-		ShortHand string `json:"shorthand"`
-	} `json:"accounts"`
+	LatestSignIn *string           `json:"latest_signin,omitempty"`
+	Accounts     []opConfigAccount `json:"accounts"`
+}
+
+type opConfigAccount struct {
+	ShortHand   string `json:"shorthand"`
+	AccountUUID string `json:"accountUUID"`
+	UserUUID    string `json:"userUUID"`
+	URL         string `json:"url"`
+}
+
+// name returns an identifier op will accept for the account. v2 configs may
+// not include a shorthand, in which case the user id is preferred as v2 names
+// session variables after it, followed by the account id or url.
+func (a opConfigAccount) name() string {
+	for _, n := range []string{a.ShortHand, a.UserUUID, a.AccountUUID, a.URL} {
+		if n != "" {
+			return n
+		}
+	}
+	return ""
+}
+
+type opField struct {
+	Designation string `json:"designation,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Type        string `json:"type,omitempty"`
+	Value       string `json:"value,omitempty"`
+}
+
+type opSectionField struct {
+	K string `json:"k,omitempty"`
+	N string `json:"n,omitempty"`
+	T string `json:"t,omitempty"`
+	V string `json:"v,omitempty"`
+}
+
+// UnmarshalJSON accepts values which v1 stores as numbers, such as the expiry
+// date of a credit card
+func (f *opSectionField) UnmarshalJSON(data []byte) error {
+	type plain opSectionField
+	var raw struct {
+		plain
+		V json.RawMessage `json:"v"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*f = opSectionField(raw.plain)
+	f.V = ""
+	if len(raw.V) > 0 {
+		if err := json.Unmarshal(raw.V, &f.V); err != nil {
+			f.V = string(raw.V)
+		}
+	}
+	return nil
+}
+
+type opSection struct {
+	Name   string           `json:"name,omitempty"`
+	Title  string           `json:"title,omitempty"`
+	Fields []opSectionField `json:"fields,omitempty"`
+}
+
+type opPasswordHistory struct {
+	Value string `json:"value"`
+	Time  int64  `json:"time"`
 }
 
 type opDetails struct {
-	Fields []struct {
-		Name  string `json:"name,omitempty"`
-		Value string `json:"value,omitempty"`
-	} `json:"fields,omitempty"`
-	NotesPlain string `json:"notesPlain,omitempty"`
+	Fields          []opField           `json:"fields,omitempty"`
+	NotesPlain      string              `json:"notesPlain,omitempty"`
+	Password        string              `json:"password,omitempty"`
+	PasswordHistory []opPasswordHistory `json:"passwordHistory,omitempty"`
+	Sections        []opSection         `json:"sections,omitempty"`
+}
+
+type opOverview struct {
+	Title             string   `json:"title,omitempty"`
+	URL               string   `json:"url,omitempty"`
+	Tags              []string `json:"tags,omitempty"`
+	PasswordStrength  int      `json:"ps,omitempty"`
+	PasswordEntropy   float64  `json:"pbe,omitempty"`
+	PasswordGenerated bool     `json:"pgrng,omitempty"`
 }
 
 type opItem struct {
-	Title   string    `json:"title"`
-	Details opDetails `json:"details"`
+	UUID         string     `json:"uuid"`
+	TemplateUUID string     `json:"templateUuid"`
+	Title        string     `json:"title"`
+	CreatedAt    time.Time  `json:"createdAt"`
+	UpdatedAt    time.Time  `json:"updatedAt"`
+	Details      opDetails  `json:"details"`
+	Overview     opOverview `json:"overview"`
+	// totp is the current code, which only v2 includes in item output
+	totp string
+	// strength is the password strength rating, which only v2 includes
+	strength string
+}
+
+// userPass returns the username and password fields
+func (i opItem) userPass() (user, pass string) {
+	for _, field := range i.Details.Fields {
+		switch field.Name {
+		case "username":
+			user = field.Value
+		case "password":
+			pass = field.Value
+		}
+	}
+	return user, pass
+}
+
+// fields returns a map of field name to value for every named field
+func (i opItem) fields() map[string]string {
+	fields := make(map[string]string, len(i.Details.Fields))
+	for _, field := range i.Details.Fields {
+		if field.Name == "" {
+			continue
+		}
+		fields[field.Name] = field.Value
+	}
+	return fields
+}
+
+// trimValues removes leading and trailing whitespace from the value of every
+// field
+func (i *opItem) trimValues() {
+	for n := range i.Details.Fields {
+		i.Details.Fields[n].Value = strings.TrimSpace(i.Details.Fields[n].Value)
+	}
+	for _, s := range i.Details.Sections {
+		for n := range s.Fields {
+			s.Fields[n].V = strings.TrimSpace(s.Fields[n].V)
+		}
+	}
+}
+
+// otpURI returns the otpauth:// URI from which one-time passwords for the
+// item are generated, or an empty string if there isn't one
+func (i opItem) otpURI() string {
+	for _, field := range i.Details.Fields {
+		if field.Type == "otp" && field.Value != "" {
+			return field.Value
+		}
+	}
+	for _, section := range i.Details.Sections {
+		for _, field := range section.Fields {
+			if (strings.HasPrefix(field.N, "TOTP_") || field.K == "otp") && field.V != "" {
+				return field.V
+			}
+		}
+	}
+	return ""
 }
 
-// Op represents an op session object
+// hasTotp reports whether the item has a one-time password configured
+func (i opItem) hasTotp() bool {
+	if i.totp != "" {
+		return true
+	}
+	for _, section := range i.Details.Sections {
+		for _, field := range section.Fields {
+			if strings.HasPrefix(field.N, "TOTP_") || field.K == "otp" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Op represents an op session object. It is safe for concurrent use by
+// multiple goroutines.
 type Op struct {
-	account   string
-	envVar    string
-	password  string
-	procAttr  *syscall.SysProcAttr
-	runner    func(name string, args ...string) (cmd *exec.Cmd)
-	setEnv    string
-	url       string
-	secretKey string
-	email     string
+	account      string
+	binary       string
+	config       ConfigReader
+	envVar       string
+	password     string
+	procAttr     *syscall.SysProcAttr
+	runner       func(ctx context.Context, name string, args ...string) (cmd *exec.Cmd)
+	setEnv       string
+	vault        string
+	version      string
+	url          string
+	secretKey    string
+	email        string
+	sessionCache string
+	passwordFunc func() (string, error)
+	// mu guards envVar, setEnv and version
+	mu sync.RWMutex
+	// signinMu serialises sign-in
+	signinMu         sync.Mutex
+	env              map[string]string
+	serviceToken     string
+	globalArgs       []string
+	maxReauth        int
+	tags             []string
+	dryRun           io.Writer
+	logger           func(cmd []string, dur time.Duration, err error)
+	concurrency      int
+	timeout          time.Duration
+	stdin            io.Reader
+	stdout           io.Writer
+	stderr           io.Writer
+	archiveOnReplace bool
+	address          string
+	sessionFlag      bool
+	envPrefix        string
+	retryAttempts    int
+	retryDelay       time.Duration
+	credFiles        CredFiles
+	fuzzyMatch       bool
+	twoFactorFunc    func() (string, error)
+	cmdDecorator     func(*exec.Cmd)
+	maxOutput        int64
+	sessionToken     string
+	trimFields       bool
+	readOnly         bool
 }
 
 // Opt represents a function that can operate on an Op pointer
 type Opt func(o *Op)
 
-type config interface {
+// ConfigReader reads the contents of an op config file
+type ConfigReader interface {
 	Read() ([]byte, error)
 }
 
 // Mockable interface for reading op config
-type configer struct{}
+type configer struct {
+	path string
+	// home, if set, replaces the home directory of the user when expanding ~
+	home string
+}
 
 func (c configer) Read() ([]byte, error) {
 	var empty []byte
-	path, err := homedir.Expand(configFile)
+	file := c.path
+	if file == "" {
+		file = c.defaultFile()
+	}
+	path, err := c.expand(file)
 	if err != nil {
-		return empty, fmt.Errorf("unable to expand '%s': %v", configFile, err)
+		return empty, fmt.Errorf("unable to expand '%s': %v", file, err)
 	}
 	if _, err = os.Stat(path); os.IsNotExist(err) {
-		return empty, fmt.Errorf("the op config file %s does not exist. Please sign-in first.", configFile)
+		return empty, fmt.Errorf("the op config file %s does not exist. Please sign-in first.", path)
 	}
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -83,199 +358,1310 @@ func (c configer) Read() ([]byte, error) {
 	return data, nil
 }
 
+// expand replaces a leading ~ in path with the home directory
+func (c configer) expand(path string) (string, error) {
+	if c.home == "" || !strings.HasPrefix(path, "~") {
+		return homedir.Expand(path)
+	}
+	return filepath.Join(c.home, path[1:]), nil
+}
+
+// defaultFile returns the v1 config location unless only the v2 config
+// exists
+func (c configer) defaultFile() string {
+	if path, err := c.expand(configFile); err == nil {
+		if _, err = os.Stat(path); err == nil {
+			return configFile
+		}
+	}
+	if path, err := c.expand(configFileV2); err == nil {
+		if _, err = os.Stat(path); err == nil {
+			return configFileV2
+		}
+	}
+	return configFile
+}
+
 // declare the reader implementation here so we can override in testing
-var configImpl config = configer{}
+var configImpl ConfigReader = configer{}
 
-// getEnv return an OP_SESSION variable either set in the environment
-// or via an explicit sign-in.
-func (o *Op) getEnv() error {
-	envval := os.Getenv(o.envVar)
+// getEnv return an OP_SESSION variable either set in the environment,
+// read from the session cache or via an explicit sign-in.
+func (o *Op) getEnv(ctx context.Context) error {
+	o.signinMu.Lock()
+	defer o.signinMu.Unlock()
+	// service accounts authenticate every command with their token
+	if o.serviceToken != "" {
+		o.setSession(serviceVar, fmt.Sprintf("%s=%s", serviceVar, o.serviceToken))
+		return nil
+	}
+	envVar, _ := o.session()
+	if o.sessionToken != "" {
+		o.setSession(envVar, fmt.Sprintf("%s=%s", envVar, o.sessionToken))
+		return nil
+	}
+	envval := os.Getenv(envVar)
 	if envval != "" {
-		o.setEnv = fmt.Sprintf("%s=%s", o.envVar, envval)
+		o.setSession(envVar, fmt.Sprintf("%s=%s", envVar, envval))
 		return nil
 	}
+	if o.sessionCache == "" {
+		return o.signin(ctx)
+	}
+	ok, err := o.readSessionCache(ctx)
+	if err != nil || ok {
+		return err
+	}
+	if err := o.signin(ctx); err != nil {
+		return err
+	}
+	return o.writeSessionCache()
+}
 
-	var cmd *exec.Cmd
+// signin runs op signin and stores the resulting session
+func (o *Op) signin(ctx context.Context) error {
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	var args []string
 	// if we have url, email and secretKey defined then login without dependency on ~/.op/config existing
 	//   this is useful if running from within a container
-	if o.email != "" && o.secretKey != "" && o.url != "" {
-		cmd = o.runner("op", "signin", o.url, o.email, o.secretKey)
-
+	email, secretKey, url, err := o.accountDetails()
+	if err != nil {
+		return err
+	}
+	addAccount := email != "" && secretKey != "" && url != ""
+	if addAccount {
+		if v2 {
+			args = []string{"account", "add", "--address", url, "--email", email, "--secret-key", secretKey, "--signin"}
+		} else {
+			args = []string{"signin", url, email, secretKey}
+		}
+	} else if o.address != "" {
+		// the account has been added before but only its address is known,
+		// such as when the config isn't available
+		if v2 {
+			args = []string{"signin", "--account", o.address}
+		} else {
+			args = []string{"signin", o.address}
+		}
 	} else {
-		cmd = o.runner("op", "signin", o.account)
+		if v2 {
+			args = []string{"signin", "--account", o.account}
+		} else {
+			args = []string{"signin", o.account}
+		}
+	}
+	// the name of the session variable doesn't matter when the token is
+	// passed with --session, so op only needs to print the token
+	if o.sessionFlag {
+		args = append(args, "--raw")
+	}
+	cmd := o.command(ctx, args...)
+	if !addAccount {
 		cmd.SysProcAttr = o.procAttr
 	}
-	if o.password != "" {
+	if len(o.env) > 0 {
+		cmd.Env = append(cmd.Env, append(os.Environ(), o.extraEnv()...)...)
+	}
+	o.decorate(cmd)
+	password := o.password
+	if o.passwordFunc != nil {
+		password, err = o.passwordFunc()
+		if err != nil {
+			return fmt.Errorf("unable to retrieve password for %s: %v", o.account, err)
+		}
+	}
+	// the result of sending the password is buffered so that the goroutine
+	// can always finish, even if op exits without reading it
+	sent := make(chan error, 1)
+	// stderr is kept for the returned error as well as being passed on
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if o.stderr != nil {
+		cmd.Stderr = io.MultiWriter(&stderr, o.stderr)
+	}
+	var twoFactor *twoFactorWriter
+	if password != "" {
 		stdin, err := cmd.StdinPipe()
 		if err != nil {
 			return fmt.Errorf("unable to open stdin pipe for op: %v", err)
 		}
+		ready := make(chan struct{})
+		if o.twoFactorFunc != nil {
+			twoFactor = &twoFactorWriter{w: cmd.Stderr, stdin: stdin, fn: o.twoFactorFunc, ready: ready}
+			cmd.Stderr = twoFactor
+		}
 		go func() {
-			defer stdin.Close()
-			io.WriteString(stdin, string(o.password))
+			buf := []byte(password)
+			if twoFactor != nil {
+				// op reads the password up to the newline and then prompts
+				// for the code, so stdin is closed once the code is sent
+				buf = append(buf, '\n')
+			}
+			_, err := stdin.Write(buf)
+			// don't leave a copy of the password lying around
+			for i := range buf {
+				buf[i] = 0
+			}
+			if twoFactor == nil {
+				if cerr := stdin.Close(); err == nil {
+					err = cerr
+				}
+			}
+			close(ready)
+			sent <- err
 		}()
 	} else {
+		sent <- nil
 		cmd.Stdin = os.Stdin
+		if o.stdin != nil {
+			cmd.Stdin = o.stdin
+		}
 	}
 
 	out, err := cmd.Output()
+	// op has exited so stdin is closed and the write can't block
+	serr := <-sent
 	if err != nil {
-		return fmt.Errorf("unable to sign-in to %s: %v", o.account, err)
+		if ctx.Err() != nil {
+			return fmt.Errorf("unable to sign-in to %s: %w", o.account, ctx.Err())
+		}
+		if serr != nil {
+			return fmt.Errorf("unable to sign-in to %s: failed to send password to op: %v", o.account, serr)
+		}
+		if twoFactor != nil && twoFactor.err != nil {
+			return fmt.Errorf("unable to sign-in to %s: %v", o.account, twoFactor.err)
+		}
+		var ee *exec.ExitError
+		if !errors.As(err, &ee) {
+			return fmt.Errorf("unable to sign-in to %s: %v", o.account, err)
+		}
+		errOut := o.redact(signinOutput(stderr.String()), []string{password, secretKey})
+		return fmt.Errorf("unable to sign-in to %s: %w", o.account, &ExitError{Code: ee.ExitCode(), Stderr: errOut})
+	}
+	envVar, _ := o.session()
+	session, envVar := o.parseSignin(out, envVar, v2)
+	if session == "" {
+		return fmt.Errorf("couldn't find %s in op output", envVar)
+	}
+	if o.stdout != nil {
+		fmt.Fprint(o.stdout, o.redact(string(out), []string{session}))
+	}
+	o.setSession(envVar, fmt.Sprintf("%s=%s", envVar, session))
+	return nil
+}
+
+// parseSignin returns the session token printed by op signin and the name of
+// the variable it should be set in
+func (o *Op) parseSignin(out []byte, envVar string, v2 bool) (session, name string) {
+	if o.sessionFlag {
+		return strings.TrimSpace(string(out)), envVar
+	}
+	// v2 names the session variable after the account's user id rather than
+	// its shorthand, so accept any session variable and adopt its name. The
+	// shorthand isn't known when signing in by address either.
+	lookFor := fmt.Sprintf(`export (%s)="(.*)"`, regexp.QuoteMeta(envVar))
+	if v2 || o.address != "" {
+		lookFor = fmt.Sprintf(`export (%s\w+)="(.*)"`, regexp.QuoteMeta(o.envPrefix))
 	}
-	lookFor := fmt.Sprintf(`export %s="(.*)"`, o.envVar)
 	re := regexp.MustCompile(lookFor)
-	var session string
 	for _, line := range strings.Split(string(out), "\n") {
 		output := re.FindStringSubmatch(line)
-		if len(output) == 2 {
-			session = output[1]
-			break
+		if len(output) == 3 {
+			return output[2], output[1]
 		}
 	}
-	if session == "" {
-		return fmt.Errorf("couldn't find %s in op output", o.envVar)
+	return "", envVar
+}
+
+// signinOutput returns the stderr of op signin without any line setting a
+// session variable and with surrounding whitespace removed
+func signinOutput(errOut string) string {
+	var lines []string
+	for _, line := range strings.Split(errOut, "\n") {
+		if !strings.Contains(line, "export ") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// session returns the name of the session variable and the variable
+// formatted for use in a command environment
+func (o *Op) session() (envVar, setEnv string) {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.envVar, o.setEnv
+}
+
+// setSession updates the session variable
+func (o *Op) setSession(envVar, setEnv string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.envVar, o.setEnv = envVar, setEnv
+}
+
+// Account returns the account used by the session, either as given to
+// WithAccount or WithSignInAddress or as selected from the op config. It is
+// empty when a service account token is used.
+func (o *Op) Account() string {
+	return o.account
+}
+
+// RunOp runs an arbitrary op subcommand using the active session. This allows
+// commands that aren't otherwise wrapped by this package to be run. The output
+// is returned raw with a single trailing newline removed.
+func (o *Op) RunOp(commands ...string) ([]byte, error) {
+	return o.RunOpContext(context.Background(), commands...)
+}
+
+// RunOpContext behaves like RunOp. If ctx is cancelled before the command
+// completes, the op process is killed.
+func (o *Op) RunOpContext(ctx context.Context, commands ...string) ([]byte, error) {
+	return o.runOp(ctx, runOpts{}, commands...)
+}
+
+// runOpts controls how runOp executes a command
+type runOpts struct {
+	// stdin, if set, is written to the standard input of op
+	stdin io.Reader
+	// secrets are replaced with *** in the text of returned errors
+	secrets []string
+	// raw returns stdout exactly as written by op rather than trimming the
+	// trailing newline
+	raw bool
+	// stdout, if set, receives the output of op instead of it being returned
+	stdout *streamWriter
+}
+
+// canReplay reports whether the command can be run again, which isn't
+// possible once its output has been streamed or if its input can't be rewound
+func (opts runOpts) canReplay() bool {
+	if opts.stdout != nil && opts.stdout.n > 0 {
+		return false
+	}
+	return rewind(opts.stdin)
+}
+
+// streamWriter passes the output of op to w, keeping track of how much has
+// been written and the first error from w
+type streamWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	n, err := s.w.Write(p)
+	s.n += int64(n)
+	if err != nil && s.err == nil {
+		s.err = err
+	}
+	return n, err
+}
+
+// runOp runs op with the supplied commands, signing in again if the session
+// has expired and WithAutoReauth has been used
+func (o *Op) runOp(ctx context.Context, opts runOpts, commands ...string) ([]byte, error) {
+	if o.readOnly && destructive(commands) {
+		return []byte{}, fmt.Errorf("unable to run %s: %w", o.redact(fmt.Sprint(commands), opts.secrets), ErrReadOnly)
+	}
+	if o.dryRun != nil && destructive(commands) {
+		args := append([]string{o.binary}, o.globalArgs...)
+		args = append(args, commands...)
+		fmt.Fprintln(o.dryRun, o.redact(strings.Join(args, " "), opts.secrets))
+		return []byte{}, nil
+	}
+	var reauths, retries int
+	for {
+		_, stale := o.session()
+		start := time.Now()
+		out, err := o.execOp(ctx, opts, commands...)
+		if o.logger != nil {
+			o.logger(o.redactAll(commands, opts.secrets), time.Since(start), err)
+		}
+		switch {
+		case errors.Is(err, ErrSessionExpired) && reauths < o.maxReauth && o.serviceToken == "":
+			if !opts.canReplay() {
+				return out, err
+			}
+			reauths++
+			if rerr := o.reauth(ctx, stale); rerr != nil {
+				return out, fmt.Errorf("unable to sign-in again after session expired: %w", rerr)
+			}
+		case (errors.Is(err, ErrRateLimited) || errors.Is(err, ErrConnectionFailed)) && retries+1 < o.retryAttempts:
+			if !opts.canReplay() {
+				return out, err
+			}
+			if werr := o.backoff(ctx, retries); werr != nil {
+				return out, fmt.Errorf("%v: %w", err, werr)
+			}
+			retries++
+		default:
+			return out, err
+		}
+	}
+}
+
+// jsonNouns are the v2 object types whose get and list commands can print
+// JSON. Documents are printed as they are stored.
+var jsonNouns = map[string]bool{"item": true, "vault": true, "user": true, "group": true, "account": true}
+
+// withFormat asks for JSON output from the v2 get and list commands, which
+// may otherwise print a table. v1 always prints JSON and, like one-time
+// passwords in v2, doesn't accept the flag. It is only used for the commands
+// this package parses so that RunOp passes commands to op unchanged.
+func withFormat(commands []string) []string {
+	if len(commands) < 2 || !jsonNouns[commands[0]] || (commands[1] != "get" && commands[1] != "list") {
+		return commands
+	}
+	for _, arg := range commands {
+		if arg == "--otp" || arg == "--format" || strings.HasPrefix(arg, "--format=") {
+			return commands
+		}
+	}
+	return append(commands[:len(commands):len(commands)], "--format", "json")
+}
+
+// rewind returns input to its start so that it can be replayed, reporting
+// whether that was possible
+func rewind(input io.Reader) bool {
+	if input == nil {
+		return true
+	}
+	s, ok := input.(io.Seeker)
+	if !ok {
+		return false
+	}
+	_, err := s.Seek(0, io.SeekStart)
+	return err == nil
+}
+
+// backoff waits before retry n, doubling the delay given to WithRetry each
+// time with up to half of it added at random. An error is returned if ctx is
+// done first.
+func (o *Op) backoff(ctx context.Context, n int) error {
+	delay := o.retryDelay << uint(n)
+	if half := int64(delay / 2); half > 0 {
+		delay += time.Duration(rand.Int63n(half))
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// readCommands are the top-level commands and actions which only read from
+// an account
+var readCommands = map[string]bool{
+	"--version": true,
+	"signin":    true,
+	"signout":   true,
+	"whoami":    true,
+	"read":      true,
+	"inject":    true,
+	"run":       true,
+	"get":       true,
+	"list":      true,
+}
+
+// nestedNouns are the v2 object types which can follow another, as in
+// vault user list
+var nestedNouns = map[string][]string{"vault": {"user", "group"}, "group": {"user"}, "item": {"template"}}
+
+// destructive reports whether commands might modify an account. Only commands
+// known to read are allowed, so that any new or unusual command is treated as
+// a change. The action is the first argument for v1 and follows the object
+// types for v2.
+func destructive(commands []string) bool {
+	if len(commands) == 0 || readCommands[commands[0]] {
+		return false
+	}
+	if len(commands) > 1 && (commands[1] == "get" || commands[1] == "list") {
+		return false
+	}
+	if len(commands) > 2 && (commands[2] == "get" || commands[2] == "list") {
+		for _, noun := range nestedNouns[commands[0]] {
+			if commands[1] == noun {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// reauth signs in again unless another goroutine has already replaced the
+// stale session
+func (o *Op) reauth(ctx context.Context, stale string) error {
+	o.signinMu.Lock()
+	defer o.signinMu.Unlock()
+	if _, setEnv := o.session(); setEnv != stale {
+		return nil
+	}
+	if err := o.signin(ctx); err != nil {
+		return err
+	}
+	if o.sessionCache != "" {
+		return o.writeSessionCache()
 	}
-	o.setEnv = fmt.Sprintf("%s=%s", o.envVar, session)
 	return nil
 }
 
-func (o *Op) runOp(commands ...string) ([]byte, error) {
-	cmdEnv := os.Environ()
-	cmdEnv = append(cmdEnv, o.setEnv)
-	cmd := o.runner("op", commands...)
+// execOp runs op once with the supplied commands
+func (o *Op) execOp(ctx context.Context, opts runOpts, commands ...string) ([]byte, error) {
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	envVar, _ := o.session()
+	flags, env := o.sessionArgs()
+	cmdEnv := append(os.Environ(), env...)
+	cmdEnv = append(cmdEnv, o.extraEnv()...)
+	cmd := o.command(ctx, append(flags, commands...)...)
 	cmd.SysProcAttr = o.procAttr
 	// append instead of replacing here as testing can set
 	// an env var before we get here
 	cmd.Env = append(cmd.Env, cmdEnv...)
-	cmdOut, err := cmd.CombinedOutput()
+	o.decorate(cmd)
+	if opts.stdin != nil {
+		pipe, err := cmd.StdinPipe()
+		if err != nil {
+			return []byte{}, fmt.Errorf("unable to open stdin pipe for op: %v", err)
+		}
+		// the input may be rewound for another attempt once this returns, so
+		// wait until it is no longer being read. The pipe is closed when op
+		// exits, so the copy can't block after that.
+		copied := make(chan struct{})
+		defer func() { <-copied }()
+		go func() {
+			defer close(copied)
+			defer pipe.Close()
+			io.Copy(pipe, opts.stdin)
+		}()
+	}
+	// op writes diagnostics to stderr so they're kept apart from the output
+	stdout := &limitedBuffer{max: o.maxOutput}
+	stderr := &limitedBuffer{max: o.maxOutput}
+	cmd.Stdout = stdout
+	if opts.stdout != nil {
+		cmd.Stdout = opts.stdout
+	}
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	cmdOut := stdout.Bytes()
+	secrets := opts.secrets
 	if err != nil {
-		if authRequired.FindString(string(cmdOut)) != "" {
-			return []byte{}, fmt.Errorf("found stale %s variable in environment", o.envVar)
+		errOut := stderr.String()
+		shown := o.redact(fmt.Sprint(commands), secrets)
+		if ctx.Err() != nil {
+			return []byte{}, fmt.Errorf("error running %s: %w", shown, ctx.Err())
+		}
+		if opts.stdout != nil && opts.stdout.err != nil {
+			return []byte{}, fmt.Errorf("error running %s: unable to write output: %v", shown, opts.stdout.err)
 		}
-		return cmdOut, fmt.Errorf("error running %s: %s", commands, cmdOut)
+		if stdout.exceeded || stderr.exceeded {
+			return []byte{}, fmt.Errorf("error running %s: %w: more than %d bytes written", shown, ErrOutputTooLarge, o.maxOutput)
+		}
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, os.ErrNotExist) {
+			return []byte{}, fmt.Errorf("unable to find op binary '%s': %w: %v", o.binary, ErrOpNotInstalled, err)
+		}
+		var ee *exec.ExitError
+		if !errors.As(err, &ee) {
+			return []byte{}, fmt.Errorf("error running %s: %v", shown, err)
+		}
+		exitErr := &ExitError{Code: ee.ExitCode(), Stderr: o.redact(errOut, secrets)}
+		exitErr.err, exitErr.OpCode = classify(errOut)
+		switch exitErr.err {
+		case ErrSessionExpired:
+			exitErr.quiet = true
+			return []byte{}, fmt.Errorf("found stale %s variable in environment for account %s: %w", envVar, o.account, exitErr)
+		case ErrItemNotFound:
+			exitErr.quiet = true
+		}
+		return cmdOut, fmt.Errorf("error running %s: %w", shown, exitErr)
+	}
+	if !opts.raw {
+		cmdOut = trimNewline(cmdOut)
 	}
-	if len(cmdOut) > 0 {
-		last := len(cmdOut) - 1
-		if cmdOut[last] == newLine {
-			cmdOut = cmdOut[:last]
+	return cmdOut, nil
+}
+
+// sessionArgs returns the flags and environment variables which pass the
+// session to op. With WithSessionFlag the token is passed with --session so
+// that it isn't in the environment of op or any command it runs. No flag is
+// passed before there is a session.
+func (o *Op) sessionArgs() (flags, env []string) {
+	envVar, setEnv := o.session()
+	if o.sessionFlag && envVar != serviceVar {
+		if token := strings.TrimPrefix(setEnv, envVar+"="); token != "" {
+			flags = []string{"--session", token}
 		}
+		return flags, nil
+	}
+	return nil, []string{setEnv}
+}
+
+// limitedBuffer is a buffer which refuses writes once it holds max bytes.
+// Once a write fails the output of op is no longer read, so op exits rather
+// than being buffered indefinitely.
+type limitedBuffer struct {
+	// buf isn't embedded as its ReadFrom would be used to bypass Write
+	buf      bytes.Buffer
+	max      int64
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.max > 0 && int64(b.buf.Len()+len(p)) > b.max {
+		b.exceeded = true
+		return 0, ErrOutputTooLarge
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) Bytes() []byte {
+	return b.buf.Bytes()
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
+
+// trimNewline removes a single trailing line ending, which may be \n, \r\n
+// or \r
+func trimNewline(out []byte) []byte {
+	if n := len(out); n > 0 && out[n-1] == newLine {
+		out = out[:n-1]
+	}
+	if n := len(out); n > 0 && out[n-1] == carriageRet {
+		out = out[:n-1]
+	}
+	return out
+}
+
+// withTimeout returns a context which expires after the duration given to
+// WithTimeout, if any
+func (o *Op) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, o.timeout)
+}
+
+// command returns a Cmd which runs op with any global arguments followed by args
+func (o *Op) command(ctx context.Context, args ...string) *exec.Cmd {
+	cmdArgs := make([]string, 0, len(o.globalArgs)+len(args))
+	cmdArgs = append(cmdArgs, o.globalArgs...)
+	cmdArgs = append(cmdArgs, args...)
+	return o.runner(ctx, o.binary, cmdArgs...)
+}
+
+// decorate passes cmd to the function given to WithCmdDecorator, if any
+func (o *Op) decorate(cmd *exec.Cmd) {
+	if o.cmdDecorator != nil {
+		o.cmdDecorator(cmd)
+	}
+}
+
+// extraEnv returns the variables given to WithEnv in a stable order
+func (o *Op) extraEnv() []string {
+	keys := make([]string, 0, len(o.env))
+	for k := range o.env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	env := make([]string, 0, len(keys))
+	for _, k := range keys {
+		env = append(env, fmt.Sprintf("%s=%s", k, o.env[k]))
+	}
+	return env
+}
+
+// redact replaces any secrets, including the sign-in password, found in text
+func (o *Op) redact(text string, secrets []string) string {
+	for _, secret := range append(secrets, o.password, o.serviceToken) {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, "***")
+		}
+	}
+	return text
+}
+
+// redactAll returns a copy of args with any secrets redacted
+func (o *Op) redactAll(args, secrets []string) []string {
+	redacted := make([]string, len(args))
+	for n, arg := range args {
+		redacted[n] = o.redact(arg, secrets)
+	}
+	return redacted
+}
+
+// withTags appends the --tags flag to commands if tags have been set
+func (o *Op) withTags(commands ...string) []string {
+	if len(o.tags) == 0 {
+		return commands
+	}
+	return append(commands, "--tags", strings.Join(o.tags, ","))
+}
+
+// withVault appends the --vault flag to commands if a vault has been set
+func (o *Op) withVault(commands ...string) []string {
+	if o.vault == "" {
+		return commands
+	}
+	return append(commands, "--vault", o.vault)
+}
+
+// getRaw returns the JSON output of op get and whether it is in the v2 format
+func (o *Op) getRaw(ctx context.Context, itemType, item string) ([]byte, bool, error) {
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	args := itemCommand(v2, "get", itemType, item)
+	out, err := o.RunOpContext(ctx, withFormat(o.withVault(args...))...)
+	if err != nil {
+		if o.fuzzyMatch && itemType == "item" && errors.Is(err, ErrItemNotFound) {
+			err = o.suggest(ctx, item, err)
+		}
+		return nil, v2, err
+	}
+	return out, v2, nil
+}
+
+// get unmarshals the output of op get into v. Items unmarshalled into an
+// *opItem are converted from the v2 format if necessary; any other v receives
+// the output as is.
+func (o *Op) get(ctx context.Context, itemType, item string, v interface{}) error {
+	out, v2, err := o.getRaw(ctx, itemType, item)
+	if err != nil {
+		return err
+	}
+	if oi, ok := v.(*opItem); ok {
+		*oi, err = unmarshalItem(out, v2)
+		if o.trimFields {
+			oi.trimValues()
+		}
+	} else {
+		err = json.Unmarshal(out, v)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to unmarshal %s data: %v", itemType, err)
+	}
+	return nil
+}
+
+// getItem returns an item in the v1 layout
+func (o *Op) getItem(ctx context.Context, item string) (oi opItem, err error) {
+	err = o.get(ctx, "item", item, &oi)
+	return oi, err
+}
+
+func (o *Op) delete(itemType, item string) error {
+	return o.remove(itemType, item, false, false)
+}
+
+// remove deletes or archives an item. Items which don't exist are ignored
+// unless mustExist is set.
+func (o *Op) remove(itemType, item string, archive, mustExist bool) error {
+	v2, err := o.isV2(context.Background())
+	if err != nil {
+		return err
+	}
+	args := itemCommand(v2, "delete", itemType, item)
+	if archive {
+		args = append(args, "--archive")
+	}
+	if _, err := o.RunOp(o.withVault(args...)...); err != nil {
+		if errors.Is(err, ErrItemNotFound) && !mustExist {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// deleteOptions holds the settings for DeleteItem
+type deleteOptions struct {
+	mustExist bool
+}
+
+// DeleteOpt changes how DeleteItem behaves
+type DeleteOpt func(*deleteOptions)
+
+// MustExist makes deleting an item which doesn't exist an error wrapping
+// ErrItemNotFound
+func MustExist() DeleteOpt {
+	return func(d *deleteOptions) {
+		d.mustExist = true
+	}
+}
+
+// DeleteItem permanently deletes an item. By default, deleting an item which
+// doesn't exist is not an error so that deletes can be repeated safely.
+func (o *Op) DeleteItem(item string, opts ...DeleteOpt) error {
+	var do deleteOptions
+	for _, opt := range opts {
+		opt(&do)
+	}
+	return o.remove("item", item, false, do.mustExist)
+}
+
+// DeleteItemByUUID permanently deletes the item with the given UUID. The item
+// is looked up first so that an item titled uuid is never deleted instead.
+func (o *Op) DeleteItemByUUID(uuid string, opts ...DeleteOpt) error {
+	var do deleteOptions
+	for _, opt := range opts {
+		opt(&do)
+	}
+	if _, err := o.getByUUID(context.Background(), uuid); err != nil {
+		if errors.Is(err, ErrItemNotFound) && !do.mustExist {
+			return nil
+		}
+		return err
+	}
+	return o.remove("item", uuid, false, do.mustExist)
+}
+
+// set creates an item and returns its UUID
+func (o *Op) set(itemType, item, category string, detail opDetails) (string, error) {
+	if err := validateItem(item, category); err != nil {
+		return "", err
+	}
+	return o.create(itemType, category, detail, o.withVault(o.withTags("--title", item)...)...)
+}
+
+// create creates an item of category with detail as its template, passing op
+// any further flags, and returns the UUID of the new item
+func (o *Op) create(itemType, category string, detail opDetails, flags ...string) (string, error) {
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return "", err
+	}
+	var template []byte
+	var commands []string
+	if v2 {
+		template, err = json.Marshal(detail.toV2())
+		commands = itemCommand(v2, "create", itemType, "--category", category, "--format", "json")
+	} else {
+		template, err = json.Marshal(detail)
+		commands = itemCommand(v2, "create", itemType, category)
+	}
+	if err != nil {
+		return "", err
+	}
+	commands = append(commands, flags...)
+
+	secrets := []string{string(template), detail.NotesPlain}
+	for _, field := range detail.Fields {
+		if field.Type == "P" {
+			secrets = append(secrets, field.Value)
+		}
+	}
+	for _, section := range detail.Sections {
+		for _, field := range section.Fields {
+			secrets = append(secrets, field.V)
+		}
+	}
+	out, err := o.runWithTemplate(ctx, template, secrets, commands...)
+	if err != nil {
+		return "", err
+	}
+	return parseUUID(out)
+}
+
+// runWithTemplate runs commands with the --template flag naming a private temp
+// file which holds template. op only reads templates from files, and passing
+// the item as an argument would make its secrets visible in the process list.
+func (o *Op) runWithTemplate(ctx context.Context, template []byte, secrets []string, commands ...string) ([]byte, error) {
+	path, err := writeTemp("op-template-", template)
+	if err != nil {
+		return nil, fmt.Errorf("unable to write item template: %v", err)
+	}
+	defer os.Remove(path)
+	args := append(commands[:len(commands):len(commands)], "--template", path)
+	return o.runOp(ctx, runOpts{secrets: secrets}, args...)
+}
+
+// writeTemp writes content to a new temp file, which only the current user can
+// read, and returns its path. The caller must remove the file.
+func writeTemp(prefix string, content []byte) (string, error) {
+	f, err := ioutil.TempFile("", prefix)
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(content)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// parseUUID returns the UUID from the output of a create command
+func parseUUID(out []byte) (string, error) {
+	// nothing is output when WithDryRun is used
+	if len(out) == 0 {
+		return "", nil
+	}
+	var created struct {
+		UUID string `json:"uuid"`
+		ID   string `json:"id"`
+	}
+	if err := json.Unmarshal(out, &created); err != nil {
+		return "", fmt.Errorf("unable to unmarshal create output: %v", err)
+	}
+	if created.UUID == "" {
+		return created.ID, nil
+	}
+	return created.UUID, nil
+}
+
+// getByUUID returns the item with the given UUID. An error is returned if op
+// resolved uuid to an item with a different UUID, such as one titled uuid.
+func (o *Op) getByUUID(ctx context.Context, uuid string) (oi opItem, err error) {
+	oi, err = o.getItem(ctx, uuid)
+	if err != nil {
+		return oi, err
+	}
+	if oi.UUID != uuid {
+		return opItem{}, fmt.Errorf("item '%s': %w", uuid, ErrItemNotFound)
+	}
+	return oi, nil
+}
+
+// ArchiveItem moves an item to the Archive, from where it can be restored in
+// the 1Password apps. Archiving an item which doesn't exist is not an error.
+func (o *Op) ArchiveItem(item string) error {
+	return o.remove("item", item, true, false)
+}
+
+// GetUserPass returns the username and password from an item from the active
+// session. The item may be given by title or UUID. Titles are not unique so a
+// UUID should be used when several items share the same title.
+func (o *Op) GetUserPass(item string) (user, pass string, err error) {
+	return o.GetUserPassContext(context.Background(), item)
+}
+
+// GetUserPassContext returns the username and password from an item from the
+// active session. The op process is killed if ctx is cancelled.
+func (o *Op) GetUserPassContext(ctx context.Context, item string) (user, pass string, err error) {
+	i, err := o.getItem(ctx, item)
+	if err != nil {
+		return "", "", err
+	}
+	user, pass = i.userPass()
+	if user == "" || pass == "" {
+		return "", "", fmt.Errorf("couldn't find username and password in '%s'", item)
+	}
+	return user, pass, nil
+}
+
+// GetUserPassByUUID returns the username and password from the item with the
+// given UUID. Unlike GetUserPass, an item titled uuid is never matched.
+func (o *Op) GetUserPassByUUID(uuid string) (user, pass string, err error) {
+	i, err := o.getByUUID(context.Background(), uuid)
+	if err != nil {
+		return "", "", err
+	}
+	user, pass = i.userPass()
+	if user == "" || pass == "" {
+		return "", "", fmt.Errorf("couldn't find username and password in '%s'", uuid)
+	}
+	return user, pass, nil
+}
+
+// GetField returns the value of the named field from an item. The field name
+// is matched case-insensitively.
+func (o *Op) GetField(item, fieldName string) (string, error) {
+	i, err := o.getItem(context.Background(), item)
+	if err != nil {
+		return "", err
+	}
+	for _, field := range i.Details.Fields {
+		if strings.EqualFold(field.Name, fieldName) {
+			return field.Value, nil
+		}
+	}
+	return "", fmt.Errorf("couldn't find '%s' in '%s': %w", fieldName, item, ErrFieldNotFound)
+}
+
+// GetAllFields returns a map of field name to value for every named field in
+// an item. Fields without a name are skipped.
+func (o *Op) GetAllFields(item string) (map[string]string, error) {
+	i, err := o.getItem(context.Background(), item)
+	if err != nil {
+		return nil, err
+	}
+	return i.fields(), nil
+}
+
+// ListFields returns the names of the fields of an item, in the order op
+// lists them, without their values. These are the names accepted by GetField.
+// Fields without a name are skipped.
+func (o *Op) ListFields(item string) ([]string, error) {
+	i, err := o.getItem(context.Background(), item)
+	if err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for _, field := range i.Details.Fields {
+		if field.Name != "" {
+			names = append(names, field.Name)
+		}
+	}
+	return names, nil
+}
+
+// GetURL returns the website URL from a login item
+func (o *Op) GetURL(item string) (string, error) {
+	i, err := o.getItem(context.Background(), item)
+	if err != nil {
+		return "", err
+	}
+	if i.Overview.URL == "" {
+		return "", fmt.Errorf("couldn't find a URL in '%s': %w", item, ErrFieldNotFound)
+	}
+	return i.Overview.URL, nil
+}
+
+// GetUUID returns the UUID of an item. Unlike titles, UUIDs always identify
+// a single item.
+func (o *Op) GetUUID(item string) (string, error) {
+	i, err := o.getItem(context.Background(), item)
+	if err != nil {
+		return "", err
+	}
+	return i.UUID, nil
+}
+
+// GetTags returns the tags of an item
+func (o *Op) GetTags(item string) ([]string, error) {
+	i, err := o.getItem(context.Background(), item)
+	if err != nil {
+		return nil, err
+	}
+	return i.Overview.Tags, nil
+}
+
+// GetItemJSON returns the item exactly as output by op so that callers can
+// unmarshal details not otherwise exposed by this package. The layout depends
+// on the version of op in use.
+func (o *Op) GetItemJSON(item string) (json.RawMessage, error) {
+	out, _, err := o.getRaw(context.Background(), "item", item)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(out), nil
+}
+
+// GetTotp returns the totp for an item from the active session
+func (o *Op) GetTotp(item string) (totp string, err error) {
+	return o.GetTotpContext(context.Background(), item)
+}
+
+// GetTotpContext returns the totp for an item from the active session. The op
+// process is killed if ctx is cancelled.
+func (o *Op) GetTotpContext(ctx context.Context, item string) (totp string, err error) {
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return "", err
+	}
+	args := []string{"get", "totp", item}
+	if v2 {
+		args = []string{"item", "get", item, "--otp"}
+	}
+	out, err := o.RunOpContext(ctx, o.withVault(args...)...)
+	if err != nil {
+		return "", fmt.Errorf("cannot get totp for %s: %w", item, err)
+	}
+	return string(out), nil
+}
+
+// totpPeriod is the standard period after which a totp changes
+const totpPeriod = 30 * time.Second
+
+// GetTotpDetail returns the totp for an item along with the time remaining
+// until it changes. The standard 30 second period is assumed.
+func (o *Op) GetTotpDetail(item string) (code string, expiresIn time.Duration, err error) {
+	code, err = o.GetTotpContext(context.Background(), item)
+	if err != nil {
+		return "", 0, err
+	}
+	return code, totpPeriod - time.Duration(time.Now().UnixNano())%totpPeriod, nil
+}
+
+// GetOTPAuthURI returns the otpauth:// URI of an item's one-time password,
+// which can be used to add the secret to another authenticator. An error
+// wrapping ErrFieldNotFound is returned if the item has no one-time password.
+func (o *Op) GetOTPAuthURI(item string) (string, error) {
+	i, err := o.getItem(context.Background(), item)
+	if err != nil {
+		return "", err
+	}
+	uri := i.otpURI()
+	if uri == "" {
+		return "", fmt.Errorf("no one-time password in '%s': %w", item, ErrFieldNotFound)
 	}
-	return cmdOut, nil
+	return uri, nil
 }
 
-func (o *Op) get(itemType, item string) (oi opItem, err error) {
-	out, err := o.runOp("get", itemType, item)
+// GetSecureNote returns a Secret Note by passing in the item name
+func (o *Op) GetSecureNote(item string) (string, error) {
+	i, err := o.getItem(context.Background(), item)
 	if err != nil {
-		return oi, err
+		return "", err
 	}
-	var i opItem
-	err = json.Unmarshal(out, &i)
+
+	return i.Details.NotesPlain, nil
+}
+
+// GetDocument returns the contents of a document item exactly as stored.
+// Documents larger than the limit set by WithMaxOutputSize, 10MB by default,
+// fail with an error wrapping ErrOutputTooLarge. Use GetDocumentTo to stream
+// larger documents instead.
+func (o *Op) GetDocument(item string) ([]byte, error) {
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
 	if err != nil {
-		return oi, fmt.Errorf("unable to unmarshal item data: %v", err)
+		return nil, err
 	}
-	return i, nil
+	return o.runOp(ctx, runOpts{raw: true}, o.withVault(itemCommand(v2, "get", "document", item)...)...)
 }
 
-func (o *Op) delete(itemType, item string) error {
-	if cmdOut, err := o.runOp("delete", itemType, item); err != nil {
-		if doesNotExist.FindString(string(cmdOut)) != "" {
-			return nil
-		}
+// GetDocumentTo writes the contents of a document item to w as op downloads
+// it, rather than holding it in memory as GetDocument does, so the limit set
+// by WithMaxOutputSize doesn't apply. If op fails part way through, w may
+// already have been written to.
+func (o *Op) GetDocumentTo(item string, w io.Writer) error {
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
+	if err != nil {
 		return err
 	}
-	return nil
+	_, err = o.runOp(ctx, runOpts{stdout: &streamWriter{w: w}}, o.withVault(itemCommand(v2, "get", "document", item)...)...)
+	return err
 }
 
-func (o *Op) set(itemType, item, category string, detail opDetails) error {
+// SetDocument creates new or replaces existing document items
+func (o *Op) SetDocument(item string, content []byte) error {
+	return o.SetNamedDocument(item, item, content)
+}
 
-	// Marshal oi into string then encode
-	encoded, err := encode(detail)
+// SetNamedDocument creates new or replaces existing document items, storing
+// content with the given filename
+func (o *Op) SetNamedDocument(item, filename string, content []byte) error {
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
 	if err != nil {
 		return err
 	}
 
-	if _, err := o.runOp("create", itemType, category, encoded, "--title", item); err != nil {
+	// op reads documents from disk so write the content to a private temp
+	// file which is always removed
+	path, err := writeTemp("op-document-", content)
+	if err != nil {
+		return fmt.Errorf("unable to write temp file for document: %v", err)
+	}
+	defer os.Remove(path)
+
+	if err := o.remove("document", item, o.archiveOnReplace, false); err != nil {
+		return err
+	}
+	nameFlag := "--filename"
+	if v2 {
+		nameFlag = "--file-name"
+	}
+	args := itemCommand(v2, "create", "document", path, "--title", item, nameFlag, filename)
+	if _, err := o.RunOpContext(ctx, o.withVault(o.withTags(args...)...)...); err != nil {
 		return err
 	}
 	return nil
 }
 
-// GetUserPass returns the username and password from an item from the active session
-func (o *Op) GetUserPass(item string) (user, pass string, err error) {
-	i, err := o.get("item", item)
+// SetSecureNote creates new or replaces existing secure notes and returns the
+// UUID of the new item
+func (o *Op) SetSecureNote(item, note string) (string, error) {
+	if err := validateItem(item, "Secure Note"); err != nil {
+		return "", err
+	}
+
+	// 1Password doesn't replace existing items automatically
+	//  so we will need to delete any existing items first.
+	if err := o.remove("item", item, o.archiveOnReplace, false); err != nil {
+		return "", err
+	}
+
+	detail := opDetails{NotesPlain: note}
+	return o.set("item", item, "Secure Note", detail)
+}
+
+// UpdateSecureNote changes the text of an existing item's note in place,
+// preserving its uuid, tags and history, or creates a new secure note if the
+// item doesn't exist. Use SetSecureNote to replace the item instead. v1 of op
+// can only be given the new note as an argument, so with v1 the note is
+// visible in the process list while op runs.
+func (o *Op) UpdateSecureNote(item, note string) error {
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
 	if err != nil {
-		return "", "", err
+		return err
 	}
-	for _, field := range i.Details.Fields {
-		switch field.Name {
-		case "username":
-			user = field.Value
-		case "password":
-			pass = field.Value
-		}
+	if v2 {
+		err = o.editNote(ctx, item, note)
+	} else {
+		err = o.EditField(item, "notesPlain", note)
 	}
-	if user == "" || pass == "" {
-		return "", "", fmt.Errorf("couldn't find username and password in '%s'", item)
+	if !errors.Is(err, ErrItemNotFound) {
+		return err
 	}
-	return user, pass, nil
+	detail := opDetails{NotesPlain: note}
+	_, err = o.set("item", item, "Secure Note", detail)
+	return err
 }
 
-// GetTotp returns the totp for an item from the active session
-func (o *Op) GetTotp(item string) (totp string, err error) {
-	out, err := o.runOp("get", "totp", item)
-	if err != nil {
-		return "", fmt.Errorf("cannot get totp for %s: %v", item, err)
+// editNote changes the note of an item by editing it with a v2 template
+func (o *Op) editNote(ctx context.Context, item, note string) error {
+	match := func(field map[string]interface{}) bool {
+		return field["purpose"] == "NOTES"
 	}
-	return string(out), nil
+	return o.editTemplate(ctx, item, note, match, opTemplateField{ID: "notesPlain", Type: "STRING", Purpose: "NOTES", Label: "notesPlain"})
 }
 
-// GetSecureNote returns a Secret Note by passing in the item name
-func (o *Op) GetSecureNote(item string) (string, error) {
-	i, err := o.get("item", item)
+// editTemplate sets value in the fields of a v2 item for which match returns
+// true, adding field if there are none, and edits the item with a template.
+// This keeps the value out of the arguments given to op.
+func (o *Op) editTemplate(ctx context.Context, item, value string, match func(map[string]interface{}) bool, field opTemplateField) error {
+	out, _, err := o.getRaw(ctx, "item", item)
 	if err != nil {
-		return "", err
+		return err
 	}
-
-	return i.Details.NotesPlain, nil
+	// the item is edited as generic JSON so that nothing op sent is dropped
+	var i map[string]interface{}
+	if err := json.Unmarshal(out, &i); err != nil {
+		return fmt.Errorf("unable to unmarshal item data: %v", err)
+	}
+	fields, _ := i["fields"].([]interface{})
+	found := false
+	for _, f := range fields {
+		if existing, ok := f.(map[string]interface{}); ok && match(existing) {
+			existing["value"] = value
+			found = true
+		}
+	}
+	if !found {
+		field.Value = value
+		i["fields"] = append(fields, field)
+	}
+	template, err := json.Marshal(i)
+	if err != nil {
+		return err
+	}
+	id, _ := i["id"].(string)
+	_, err = o.runWithTemplate(ctx, template, []string{string(template), value}, o.withVault("item", "edit", id)...)
+	return err
 }
 
-// SetSecureNote creates new or replaces existing secure notes
-func (o *Op) SetSecureNote(item, note string) error {
+// SetUserPass creates new or replaces existing logins and returns the UUID of
+// the new item
+func (o *Op) SetUserPass(item, user, pass string) (string, error) {
+	if err := validateItem(item, "Login"); err != nil {
+		return "", err
+	}
 
 	// 1Password doesn't replace existing items automatically
 	//  so we will need to delete any existing items first.
-	if err := o.delete("item", item); err != nil {
-		return err
+	if err := o.remove("item", item, o.archiveOnReplace, false); err != nil {
+		return "", err
 	}
 
-	detail := opDetails{NotesPlain: note}
-	if err := o.set("item", item, "Secure Note", detail); err != nil {
+	detail := opDetails{
+		Fields: []opField{
+			{Designation: "username", Name: "username", Type: "T", Value: user},
+			{Designation: "password", Name: "password", Type: "P", Value: pass},
+		},
+	}
+	return o.set("item", item, "Login", detail)
+}
+
+// EditField updates a single field of an existing item in place, preserving
+// its history and metadata. A field which doesn't exist is added as a text
+// field. v1 of op can only be given the new value as an argument, so with v1
+// the value is visible in the process list while op runs.
+func (o *Op) EditField(item, fieldName, value string) error {
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return err
+	}
+	if v2 {
+		match := func(field map[string]interface{}) bool {
+			return field["label"] == fieldName || field["id"] == fieldName
+		}
+		return o.editTemplate(ctx, item, value, match, opTemplateField{ID: fieldName, Type: "STRING", Label: fieldName})
+	}
+	// v1 has no way to read assignments from a file or stdin, so the value
+	// has to be passed as an argument. It is redacted from any errors.
+	assignment := fmt.Sprintf("%s=%s", fieldEscaper.Replace(fieldName), value)
+	args := itemCommand(v2, "edit", "item", item, assignment)
+	if _, err := o.runOp(ctx, runOpts{secrets: []string{value}}, o.withVault(args...)...); err != nil {
 		return err
 	}
 	return nil
 }
 
-// GetUserPass is a top-level function that wraps the underlying method from Op
-func GetUserPass(item string) (user, pass string, err error) {
-	o, err := New()
+// fieldEscaper escapes the characters op treats specially in field assignments
+var fieldEscaper = strings.NewReplacer(`\`, `\\`, ".", `\.`, "=", `\=`)
+
+// Signout revokes the active session. If forget is true, op will also remove
+// the account details from its config. If the session had already expired, the
+// returned error wraps ErrSessionExpired. The Op must be re-created with New
+// before it can be used again.
+func (o *Op) Signout(forget bool) error {
+	args := []string{"signout"}
+	if forget {
+		args = append(args, "--forget")
+	}
+	_, err := o.RunOp(args...)
+	envVar, _ := o.session()
+	o.setSession(envVar, "")
+	return err
+}
+
+// GetUserPass is a top-level function that wraps the underlying method from Op.
+// Options such as WithAccount or WithVault are passed to New.
+func GetUserPass(item string, opts ...Opt) (user, pass string, err error) {
+	o, err := New(opts...)
 	if err != nil {
 		return "", "", err
 	}
 	return o.GetUserPass(item)
 }
 
-// GetTotp is a top-level function that wraps the underlying method from Op
-func GetTotp(item string) (totp string, err error) {
-	o, err := New()
+// GetTotp is a top-level function that wraps the underlying method from Op.
+// Options such as WithAccount or WithVault are passed to New.
+func GetTotp(item string, opts ...Opt) (totp string, err error) {
+	o, err := New(opts...)
 	if err != nil {
 		return "", err
 	}
 	return o.GetTotp(item)
 }
 
-// GetUserPassTotp gets all three entries for an item
-func GetUserPassTotp(item string) (user, pass, totp string, err error) {
-	o, err := New()
+// GetUserPassTotp gets all three entries for an item. Options such as
+// WithAccount or WithVault are passed to New.
+func GetUserPassTotp(item string, opts ...Opt) (user, pass, totp string, err error) {
+	o, err := New(opts...)
 	if err != nil {
 		return "", "", "", err
 	}
@@ -292,24 +1678,80 @@ func GetUserPassTotp(item string) (user, pass, totp string, err error) {
 
 // New returns a pointer to a configured Op object
 func New(opts ...Opt) (o *Op, err error) {
-	o = &Op{runner: runCmd}
-	for _, opt := range opts {
-		opt(o)
+	return NewContext(context.Background(), opts...)
+}
+
+// NewContext returns a pointer to a configured Op object. If a sign-in is
+// required, the op process is killed if ctx is cancelled.
+func NewContext(ctx context.Context, opts ...Opt) (o *Op, err error) {
+	// each Op keeps its own config reader so that it isn't affected by
+	// changes to the package default
+	o = newOp(opts...)
+	if err := o.findBinary(); err != nil {
+		return o, err
 	}
-	if o.account == "" {
-		o.account, err = getSigninFromConfig()
-		if err != nil {
-			return o, err
-		}
+	if err := o.findAccount(); err != nil {
+		return o, err
 	}
-	o.envVar = fmt.Sprintf("%s%s", envPrefix, o.account)
-	err = o.getEnv()
+	err = o.getEnv(ctx)
 	if err != nil {
 		return o, err
 	}
 	return o, nil
 }
 
+// newOp returns an Op with the defaults and opts applied
+func newOp(opts ...Opt) *Op {
+	o := &Op{binary: defaultBinary, config: configImpl, envPrefix: defaultPrefix, maxOutput: defaultMaxOutput}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// findBinary checks that the op binary exists. A custom runner may not run op
+// from this host so the binary is only checked when commands are run directly.
+func (o *Op) findBinary() error {
+	if o.runner != nil {
+		return nil
+	}
+	if _, err := exec.LookPath(o.binary); err != nil {
+		return fmt.Errorf("unable to find op binary '%s': %w: %v", o.binary, ErrOpNotInstalled, err)
+	}
+	o.runner = runCmd
+	return nil
+}
+
+// findAccount sets the account to sign-in to, reading it from the op config
+// if it wasn't given, and the name of its session variable
+func (o *Op) findAccount() (err error) {
+	if o.serviceToken != "" {
+		if o.password != "" || o.passwordFunc != nil || o.email != "" || o.secretKey != "" || o.url != "" || o.credFiles != (CredFiles{}) {
+			return fmt.Errorf("a service account token cannot be used with interactive sign-in credentials")
+		}
+		return nil
+	}
+	// an account which is added at sign-in needn't be in the config, which
+	// may not exist, so it is signed in to by address
+	if o.account == "" && o.address == "" {
+		if o.address, err = o.addAccountURL(); err != nil {
+			return err
+		}
+	}
+	if o.account == "" && o.address != "" {
+		o.account = o.address
+	}
+	if o.account == "" {
+		o.account, err = getSigninFromConfig(o.config)
+		// the account isn't needed to pass a session token with --session
+		if err != nil && o.sessionToken == "" {
+			return err
+		}
+	}
+	o.envVar = fmt.Sprintf("%s%s", o.envPrefix, o.account)
+	return nil
+}
+
 // WithAccount explicitly sets the account to sign-in to
 func WithAccount(name string) Opt {
 	return func(o *Op) {
@@ -324,6 +1766,58 @@ func WithPassword(password string) Opt {
 	}
 }
 
+// WithPasswordFunc sets a function that will be called to retrieve the
+// password only when a sign-in is required. This takes precedence over
+// WithPassword.
+func WithPasswordFunc(fn func() (string, error)) Opt {
+	return func(o *Op) {
+		o.passwordFunc = fn
+	}
+}
+
+// WithTwoFactorFunc sets a function that will be called to retrieve a
+// one-time code when op prompts for one after the password. It is only used
+// when the password is given by WithPassword or WithPasswordFunc.
+func WithTwoFactorFunc(fn func() (string, error)) Opt {
+	return func(o *Op) {
+		o.twoFactorFunc = fn
+	}
+}
+
+// WithPasswordFile reads the password from the file at path when a sign-in is
+// required, such as a credential provided by systemd's LoadCredential. An
+// error is returned if the file is unreadable or readable by other users.
+func WithPasswordFile(path string) Opt {
+	return WithPasswordFunc(func() (string, error) {
+		return readSecretFile(path)
+	})
+}
+
+// WithStdin sets the reader used as the input of an interactive sign-in, which
+// happens when no password is given. Defaults to os.Stdin. This allows
+// applications which own the terminal to collect the password themselves.
+func WithStdin(r io.Reader) Opt {
+	return func(o *Op) {
+		o.stdin = r
+	}
+}
+
+// WithStdout sets a writer which receives the output of sign-in with the
+// session token redacted
+func WithStdout(w io.Writer) Opt {
+	return func(o *Op) {
+		o.stdout = w
+	}
+}
+
+// WithStderr sets a writer which receives the error output of sign-in,
+// including any password prompt
+func WithStderr(w io.Writer) Opt {
+	return func(o *Op) {
+		o.stderr = w
+	}
+}
+
 // WithUID sets the uid that will be used when running the op command
 // Assumes the caller has privs for SYS_SETUID
 func WithUID(uid int) Opt {
@@ -336,6 +1830,36 @@ func WithUID(uid int) Opt {
 	}
 }
 
+// WithMaxOutputSize sets the most op may write to stdout or stderr for a
+// single command, which defaults to 10MB. The command fails with an error
+// wrapping ErrOutputTooLarge if the limit is exceeded. A size of zero or less
+// removes the limit. The output of GetDocumentTo is streamed, so only what op
+// writes to stderr is limited.
+func WithMaxOutputSize(n int64) Opt {
+	return func(o *Op) {
+		o.maxOutput = n
+	}
+}
+
+// WithTrimFieldValues sets whether leading and trailing whitespace, such as a
+// trailing newline, is removed from field values returned by getters like
+// GetUserPass, GetField and GetAllFields. Values are returned exactly as
+// stored by default.
+func WithTrimFieldValues(trim bool) Opt {
+	return func(o *Op) {
+		o.trimFields = trim
+	}
+}
+
+// WithCmdDecorator sets a function which is called with every op command
+// once it has been built and before it is run, so that settings such as Dir
+// or SysProcAttr can be changed. It is called after WithUID has been applied.
+func WithCmdDecorator(fn func(*exec.Cmd)) Opt {
+	return func(o *Op) {
+		o.cmdDecorator = fn
+	}
+}
+
 // WithSecretKey sets the secret key used for op signin
 func WithSecretKey(secretKey string) Opt {
 	return func(o *Op) {
@@ -350,28 +1874,270 @@ func WithEmail(email string) Opt {
 	}
 }
 
-// WithURL sets the url used for op signin
+// WithSignInAddress signs in to the account with the given address, such as
+// my.1password.com, without reading the op config to find its shorthand. The
+// account must have been added to op before. Use WithURL along with WithEmail
+// and WithSecretKey to add an account for the first time.
+func WithSignInAddress(address string) Opt {
+	return func(o *Op) {
+		o.address = address
+	}
+}
+
+// WithURL sets the url used for op signin. Along with WithEmail and
+// WithSecretKey, the account is added at sign-in and is signed in to by its
+// url without reading the op config.
 func WithURL(url string) Opt {
 	return func(o *Op) {
 		o.url = url
 	}
 }
 
-// allow specification of an alternate Cmdfunc for testing
-func withCmdFunc(f func(name string, args ...string) (cmd *exec.Cmd)) Opt {
+// WithVault restricts item lookups, creation and deletion to a single vault
+func WithVault(vault string) Opt {
+	return func(o *Op) {
+		o.vault = vault
+	}
+}
+
+// WithConfigReader sets the reader used to read the op config when the
+// account isn't given, so that it can come from somewhere other than a file
+func WithConfigReader(reader ConfigReader) Opt {
+	return func(o *Op) {
+		o.config = reader
+	}
+}
+
+// WithConfigPath sets the location of the op config file used to determine
+// the account when one isn't given. Defaults to ~/.op/config
+func WithConfigPath(path string) Opt {
+	return func(o *Op) {
+		o.config = configer{path: path}
+	}
+}
+
+// WithConfigHome reads the op config from the .op or .config/op directory
+// under dir, rather than under the home directory of the user, without
+// changing $HOME
+func WithConfigHome(dir string) Opt {
+	return func(o *Op) {
+		o.config = configer{home: dir}
+	}
+}
+
+// WithSessionCache caches the session token in the file at path so that
+// subsequent calls to New can reuse it rather than signing in again. The file
+// is created with 0600 permissions. A session cached for another account is
+// ignored.
+func WithSessionCache(path string) Opt {
+	return func(o *Op) {
+		o.sessionCache = path
+	}
+}
+
+// WithEnv sets additional environment variables for every op command without
+// modifying the environment of the calling process. These take precedence
+// over any variables of the same name already set.
+func WithEnv(kv map[string]string) Opt {
+	return func(o *Op) {
+		if o.env == nil {
+			o.env = make(map[string]string, len(kv))
+		}
+		for k, v := range kv {
+			o.env[k] = v
+		}
+	}
+}
+
+// WithDevice sets OP_DEVICE for sign-in and every command so that op sees the
+// same device each time, such as on ephemeral CI runners. op expects a 26
+// character base32 id; a warning is logged if id doesn't look like one but it
+// is used regardless.
+func WithDevice(id string) Opt {
+	return func(o *Op) {
+		if !deviceID.MatchString(id) {
+			log.Printf("op: device id '%s' is not a 26 character base32 string", id)
+		}
+		WithEnv(map[string]string{"OP_DEVICE": id})(o)
+	}
+}
+
+// WithServiceAccountToken authenticates using a service account token rather
+// than signing in. It cannot be combined with WithPassword, WithPasswordFunc,
+// WithEmail, WithSecretKey or WithURL.
+func WithServiceAccountToken(token string) Opt {
+	return func(o *Op) {
+		o.serviceToken = token
+	}
+}
+
+// WithGlobalArgs sets arguments such as --cache or --no-color which are
+// passed to op before the subcommand on every invocation
+func WithGlobalArgs(args ...string) Opt {
+	return func(o *Op) {
+		o.globalArgs = append(o.globalArgs, args...)
+	}
+}
+
+// WithNoCache stops op from using its local cache of items and vaults, so
+// that every command, including sign-in, fetches from the server. This adds
+// the latency of a round trip to the server to every lookup.
+func WithNoCache() Opt {
+	return func(o *Op) {
+		o.globalArgs = append(o.globalArgs, "--cache=false")
+	}
+}
+
+// WithAutoReauth signs in again and retries a command, up to max times, when
+// op reports that the session has expired. A failure to sign-in is returned
+// immediately rather than retried.
+func WithAutoReauth(max int) Opt {
+	return func(o *Op) {
+		o.maxReauth = max
+	}
+}
+
+// WithTags sets the tags applied to items created by the Set methods
+func WithTags(tags ...string) Opt {
+	return func(o *Op) {
+		o.tags = append(o.tags, tags...)
+	}
+}
+
+// WithArchiveOnReplace archives rather than deletes the existing item when it
+// is replaced by one of the Set methods so that it can be recovered
+func WithArchiveOnReplace() Opt {
+	return func(o *Op) {
+		o.archiveOnReplace = true
+	}
+}
+
+// WithReadOnly makes methods which would create, delete, edit or move items
+// fail with an error wrapping ErrReadOnly rather than running op. RunOp
+// refuses any command which isn't known to only read from the account, such
+// as granting vault access. Methods which only read are unaffected.
+func WithReadOnly() Opt {
+	return func(o *Op) {
+		o.readOnly = true
+	}
+}
+
+// WithDryRun prevents commands which might change the account, such as those
+// which create, delete, edit or move items, from being run. Instead, the
+// command line is written to w with any secrets redacted. Commands which only
+// read from the account are run as usual.
+func WithDryRun(w io.Writer) Opt {
+	return func(o *Op) {
+		o.dryRun = w
+	}
+}
+
+// WithConcurrency limits the number of op commands run at once by methods
+// which fetch several items, such as GetItemsByTag. Defaults to 4. Setting it
+// to 1 fetches items one at a time.
+func WithConcurrency(n int) Opt {
+	return func(o *Op) {
+		o.concurrency = n
+	}
+}
+
+// WithTimeout kills any op command, including sign-in, which runs for longer
+// than d. The error returned wraps context.DeadlineExceeded. Zero, the
+// default, means commands are never timed out. As an interactive sign-in is
+// also subject to the timeout, a password should be given when using it.
+func WithTimeout(d time.Duration) Opt {
+	return func(o *Op) {
+		o.timeout = d
+	}
+}
+
+// WithSessionFlag passes the session token to op with the --session flag
+// rather than setting it in the environment, so that it isn't inherited by
+// any processes started by op. Note that, unlike the environment, command
+// line arguments can be seen by other users on most systems. Service account
+// tokens are always set in the environment.
+func WithSessionFlag() Opt {
+	return func(o *Op) {
+		o.sessionFlag = true
+	}
+}
+
+// WithSessionToken uses a session token obtained elsewhere, such as by
+// running op signin --raw, rather than signing in. The token is passed with
+// the --session flag, as for WithSessionFlag, so the account is only needed to
+// sign-in again with WithAutoReauth.
+func WithSessionToken(token string) Opt {
+	return func(o *Op) {
+		o.sessionToken = token
+		o.sessionFlag = true
+	}
+}
+
+// WithEnvPrefix sets the prefix of the variable holding the session token,
+// which is followed by the account. Defaults to "OP_SESSION_".
+func WithEnvPrefix(prefix string) Opt {
+	return func(o *Op) {
+		o.envPrefix = prefix
+	}
+}
+
+// WithRetry runs commands up to maxAttempts times if they fail because of
+// rate limiting or a connection failure, waiting baseDelay before the first
+// retry and doubling the delay, plus some jitter, each time. Other errors are
+// never retried. Retries stop when the context of a call is done.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Opt {
+	return func(o *Op) {
+		o.retryAttempts = maxAttempts
+		o.retryDelay = baseDelay
+	}
+}
+
+// WithFuzzyMatch lists items when an item can't be found so that the error
+// can suggest items with similar titles. This costs an extra call to op so is
+// off by default.
+func WithFuzzyMatch() Opt {
+	return func(o *Op) {
+		o.fuzzyMatch = true
+	}
+}
+
+// WithLogger sets a function that is called after each op command completes
+// with the command's arguments, how long it took and any error. Secrets are
+// redacted from the arguments. A command that is retried after signing in
+// again is reported once per attempt.
+func WithLogger(fn func(cmd []string, dur time.Duration, err error)) Opt {
+	return func(o *Op) {
+		o.logger = fn
+	}
+}
+
+// WithBinaryPath sets the location of the op executable. Defaults to "op"
+// which will be resolved via $PATH
+func WithBinaryPath(path string) Opt {
+	return func(o *Op) {
+		o.binary = path
+	}
+}
+
+// WithRunner replaces the function used to create op commands. It is intended
+// for testing code which uses this package without installing op: fn can
+// return a Cmd which runs a fake that prints canned output, such as a helper
+// process in the test binary. name is the op binary, followed by its arguments.
+// The check for the op binary in New is skipped when a runner is set.
+func WithRunner(fn func(ctx context.Context, name string, args ...string) *exec.Cmd) Opt {
 	return func(o *Op) {
-		o.runner = f
+		o.runner = fn
 	}
 }
 
 // runCmd returns a properly initialized exec Cmd struct
-func runCmd(name string, args ...string) (cmd *exec.Cmd) {
-	cmd = exec.Command(name, args...)
+func runCmd(ctx context.Context, name string, args ...string) (cmd *exec.Cmd) {
+	cmd = exec.CommandContext(ctx, name, args...)
 	return cmd
 }
 
-func getSigninFromConfig() (string, error) {
-	data, err := configImpl.Read()
+func getSigninFromConfig(reader ConfigReader) (string, error) {
+	data, err := reader.Read()
 	if err != nil {
 		return "", err
 	}
@@ -380,23 +2146,15 @@ func getSigninFromConfig() (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("unable to unmarshal config data: %v", err)
 	}
-	if c.LatestSignIn != nil {
+	if c.LatestSignIn != nil && *c.LatestSignIn != "" {
 		return *c.LatestSignIn, nil
 	}
 	acctCount := len(c.Accounts)
 	if acctCount > 1 {
 		return "", fmt.Errorf("found %d accounts - please supply an explicit name", acctCount)
 	}
-	if acctCount == 1 {
-		return c.Accounts[0].ShortHand, nil
+	if acctCount == 1 && c.Accounts[0].name() != "" {
+		return c.Accounts[0].name(), nil
 	}
 	return "", fmt.Errorf("cannot determine which 1password account to use")
 }
-
-func encode(data interface{}) (string, error) {
-	bytes, err := json.Marshal(data)
-	if err != nil {
-		return "", err
-	}
-	return base64url.Encode(bytes), nil
-}
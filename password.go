@@ -0,0 +1,45 @@
+package op
+
+import (
+	"context"
+	"sort"
+)
+
+// PasswordInfo describes the password of an item
+type PasswordInfo struct {
+	// Strength is the rating given by v2 of op, such as "FANTASTIC". It is
+	// empty for v1.
+	Strength string
+	// Score is the strength of the password from 0 to 100 given by v1 of op.
+	// It is zero for v2.
+	Score int
+	// Entropy is the number of bits of entropy in the password
+	Entropy float64
+	// Generated reports whether the password was created by the 1Password
+	// password generator
+	Generated bool
+	// History holds the previous passwords of the item, newest first
+	History []string
+}
+
+// GetPasswordInfo returns the strength and history of the password of an item
+func (o *Op) GetPasswordInfo(item string) (PasswordInfo, error) {
+	i, err := o.getItem(context.Background(), item)
+	if err != nil {
+		return PasswordInfo{}, err
+	}
+	info := PasswordInfo{
+		Strength:  i.strength,
+		Score:     i.Overview.PasswordStrength,
+		Entropy:   i.Overview.PasswordEntropy,
+		Generated: i.Overview.PasswordGenerated,
+	}
+	history := i.Details.PasswordHistory
+	sort.SliceStable(history, func(a, b int) bool {
+		return history[a].Time > history[b].Time
+	})
+	for _, h := range history {
+		info.History = append(info.History, h.Value)
+	}
+	return info, nil
+}
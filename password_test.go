@@ -0,0 +1,35 @@
+package op
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"testing"
+)
+
+func TestGetPasswordInfo(t *testing.T) {
+	configImpl = mockConfiger{}
+	tests := []struct {
+		name   string
+		runner func(ctx context.Context, name string, args ...string) *exec.Cmd
+		want   string
+	}{
+		{"V1", mockCmd, "{ 100 85.38914735493256 true [oldpass olderpass]}"},
+		{"V2", mockCmdV2, "{FANTASTIC 0 85.38914735493256 true [oldpass olderpass]}"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := New(WithRunner(tt.runner))
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := o.GetPasswordInfo("FOOBAR")
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if got := fmt.Sprint(info); got != tt.want {
+				t.Fatalf("Got: %s, want: %s\n", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package op
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// referencePrefix starts every secret reference
+const referencePrefix = "op://"
+
+// Read returns the value of a secret reference such as op://vault/item/field.
+// A single trailing newline is removed. Secret references require version 2
+// of op.
+func (o *Op) Read(reference string) (string, error) {
+	out, err := o.read(reference, false)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// ReadBytes behaves like Read but returns the value exactly as output by op,
+// which makes it suitable for documents and other files.
+func (o *Op) ReadBytes(reference string) ([]byte, error) {
+	return o.read(reference, true)
+}
+
+func (o *Op) read(reference string, raw bool) ([]byte, error) {
+	if !strings.HasPrefix(reference, referencePrefix) {
+		return nil, fmt.Errorf("invalid secret reference '%s': must start with %s", reference, referencePrefix)
+	}
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !v2 {
+		return nil, fmt.Errorf("unable to read '%s': secret references require version 2 of op", reference)
+	}
+	out, err := o.runOp(ctx, runOpts{raw: raw}, "read", reference)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// Inject returns template with every secret reference, written as
+// {{ op://vault/item/field }}, replaced by its value. The template is written
+// to op over stdin. Secret references require version 2 of op.
+func (o *Op) Inject(template []byte) ([]byte, error) {
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !v2 {
+		return nil, fmt.Errorf("unable to inject secrets: secret references require version 2 of op")
+	}
+	return o.runOp(ctx, runOpts{stdin: bytes.NewReader(template), raw: true}, "inject")
+}
+
+// Run runs argv via op run with env added to its environment. Values of env
+// may be secret references, which op resolves before starting the command so
+// that secrets are never written to disk. The command shares the standard
+// input and output of the current process. If the command fails, the returned
+// error wraps an *exec.ExitError holding its exit code. Secret references
+// require version 2 of op.
+func (o *Op) Run(env map[string]string, argv []string) error {
+	if len(argv) == 0 {
+		return fmt.Errorf("no command given to run")
+	}
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return err
+	}
+	if !v2 {
+		return fmt.Errorf("unable to run %s: op run requires version 2 of op", argv[0])
+	}
+	flags, sessionEnv := o.sessionArgs()
+	cmdEnv := append(os.Environ(), sessionEnv...)
+	cmdEnv = append(cmdEnv, o.extraEnv()...)
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		cmdEnv = append(cmdEnv, fmt.Sprintf("%s=%s", k, env[k]))
+	}
+	args := append(flags, "run", "--")
+	cmd := o.command(ctx, append(args, argv...)...)
+	cmd.SysProcAttr = o.procAttr
+	cmd.Env = append(cmd.Env, cmdEnv...)
+	o.decorate(cmd)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("error running %s: %w", argv[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package op
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	configImpl = mockConfiger{}
+	o, err := New(WithRunner(mockCmdV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err := o.Read("op://Private/FOOBAR/password")
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if value != "greatpass" {
+		t.Fatalf("Got: %s, want: greatpass\n", value)
+	}
+	content, err := o.ReadBytes("op://Private/DOC/file")
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	if !bytes.Equal(content, document) {
+		t.Fatalf("Got: %v, want: %v\n", content, document)
+	}
+	if _, err := o.Read("op://Private/missing/password"); !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("Got error: %v, want: %v\n", err, ErrItemNotFound)
+	}
+	if _, err := o.Read("Private/FOOBAR/password"); err == nil {
+		t.Fatal("Expected an error for an invalid reference")
+	}
+
+	o, err = New(WithRunner(mockCmd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.Read("op://Private/FOOBAR/password"); err == nil {
+		t.Fatal("Expected an error when using v1")
+	}
+}
+
+func TestInject(t *testing.T) {
+	configImpl = mockConfiger{}
+	o, err := New(WithRunner(mockCmdV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := o.Inject([]byte("user: admin\npass: {{ op://Private/FOOBAR/password }}\n"))
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	want := "user: admin\npass: greatpass\n"
+	if string(out) != want {
+		t.Fatalf("Got: %q, want: %q\n", out, want)
+	}
+	if _, err := o.Inject([]byte("pass: {{ op://Private/missing/password }}")); !errors.Is(err, ErrItemNotFound) {
+		t.Fatalf("Got error: %v, want: %v\n", err, ErrItemNotFound)
+	}
+}
+
+func TestRun(t *testing.T) {
+	configImpl = mockConfiger{}
+	o, err := New(WithRunner(mockCmdV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"DB_PASS": "op://Private/FOOBAR/password"}
+	if err := o.Run(env, []string{"check", "DB_PASS"}); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	err = o.Run(nil, []string{"exit", "3"})
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Fatalf("Got error: %v, want exit code 3\n", err)
+	}
+	if err := o.Run(nil, nil); err == nil {
+		t.Fatal("Expected an error without a command")
+	}
+
+	// the command doesn't inherit a session passed with --session
+	o, err = New(WithSessionToken("RANDO"), WithRunner(mockCmdV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := o.Run(nil, []string{"nosession"}); err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+}
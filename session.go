@@ -0,0 +1,61 @@
+package op
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// CheckSession confirms that the session is still valid by listing vaults,
+// which doesn't fetch any secrets. If the session has expired, an error
+// wrapping ErrSessionExpired is returned. The session isn't renewed, even if
+// WithAutoReauth has been used.
+func (o *Op) CheckSession() error {
+	return o.checkSession(context.Background())
+}
+
+func (o *Op) checkSession(ctx context.Context) error {
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return err
+	}
+	args := []string{"list", "vaults"}
+	if v2 {
+		args = []string{"vault", "list"}
+	}
+	// run once, without signing in again, so that expiry is reported
+	_, err = o.execOp(ctx, runOpts{}, args...)
+	return err
+}
+
+// Identity describes the user that is signed in
+type Identity struct {
+	URL         string `json:"url"`
+	Email       string `json:"email"`
+	UserUUID    string `json:"user_uuid"`
+	AccountUUID string `json:"account_uuid"`
+	// UserType is HUMAN or SERVICE_ACCOUNT
+	UserType string `json:"user_type"`
+}
+
+// WhoAmI returns the identity of the signed in user. It requires version 2
+// of op.
+func (o *Op) WhoAmI() (Identity, error) {
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+	if !v2 {
+		return Identity{}, fmt.Errorf("unable to determine identity: op whoami requires version 2 of op")
+	}
+	out, err := o.RunOpContext(ctx, "whoami", "--format", "json")
+	if err != nil {
+		return Identity{}, err
+	}
+	var id Identity
+	if err := json.Unmarshal(out, &id); err != nil {
+		return Identity{}, fmt.Errorf("unable to unmarshal identity: %v", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,63 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestCheckSession(t *testing.T) {
+	configImpl = mockConfiger{}
+	tests := []struct {
+		name    string
+		runner  func(ctx context.Context, name string, args ...string) *exec.Cmd
+		session string
+		wantErr error
+	}{
+		{"V1", mockCmd, "", nil},
+		{"V2", mockCmdV2, "", nil},
+		{"Stale", mockCmd, "STALE", ErrSessionExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.session != "" {
+				os.Setenv("OP_SESSION_my_team", tt.session)
+				defer os.Unsetenv("OP_SESSION_my_team")
+			}
+			o, err := New(WithPassword("greatpass"), WithAutoReauth(1), WithRunner(tt.runner))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := o.CheckSession(); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Got error: %v, want: %v\n", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWhoAmI(t *testing.T) {
+	configImpl = mockConfiger{}
+	o, err := New(WithRunner(mockCmdV2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := o.WhoAmI()
+	if err != nil {
+		t.Fatal("Unexpected error:", err)
+	}
+	want := "{https://my_team.1password.com user@bar.com ABCDEF123 XYZ789 HUMAN}"
+	if got := fmt.Sprint(id); got != want {
+		t.Fatalf("Got: %s, want: %s\n", got, want)
+	}
+
+	o, err = New(WithRunner(mockCmd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := o.WhoAmI(); err == nil {
+		t.Fatal("Expected an error when using v1")
+	}
+}
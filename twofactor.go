@@ -0,0 +1,64 @@
+package op
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// twoFactorPrompts are the prompts op uses to ask for a one-time code
+var twoFactorPrompts = []string{"six-digit authentication code", "one-time password", "one-time code"}
+
+// twoFactorWriter watches the stderr of op signin for a one-time code prompt
+// and answers it with the code returned by fn. Everything written is passed
+// on to w if it is set.
+type twoFactorWriter struct {
+	w     io.Writer
+	stdin io.WriteCloser
+	fn    func() (string, error)
+	// ready is closed once the password has been written to stdin
+	ready <-chan struct{}
+	line  []byte
+	done  bool
+	err   error
+}
+
+func (t *twoFactorWriter) Write(p []byte) (int, error) {
+	if t.w != nil {
+		if _, err := t.w.Write(p); err != nil {
+			return 0, err
+		}
+	}
+	if t.done {
+		return len(p), nil
+	}
+	// prompts aren't followed by a newline and may arrive in pieces
+	t.line = append(t.line, p...)
+	lower := strings.ToLower(string(t.line))
+	for _, prompt := range twoFactorPrompts {
+		if strings.Contains(lower, prompt) {
+			t.answer()
+			return len(p), nil
+		}
+	}
+	if n := bytes.LastIndexByte(t.line, '\n'); n >= 0 {
+		t.line = t.line[n+1:]
+	}
+	return len(p), nil
+}
+
+// answer sends the one-time code to op and closes its stdin
+func (t *twoFactorWriter) answer() {
+	t.done = true
+	<-t.ready
+	defer t.stdin.Close()
+	code, err := t.fn()
+	if err != nil {
+		t.err = fmt.Errorf("unable to retrieve one-time code: %v", err)
+		return
+	}
+	if _, err := io.WriteString(t.stdin, strings.TrimSpace(code)+"\n"); err != nil {
+		t.err = fmt.Errorf("failed to send one-time code to op: %v", err)
+	}
+}
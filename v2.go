@@ -0,0 +1,231 @@
+package op
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// opItemV2 represents an item as returned by version 2 of the op CLI
+type opItemV2 struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Category  string    `json:"category"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Tags      []string  `json:"tags"`
+	Sections  []struct {
+		ID    string `json:"id"`
+		Label string `json:"label"`
+	} `json:"sections"`
+	Fields []struct {
+		ID      string `json:"id"`
+		Type    string `json:"type"`
+		Label   string `json:"label"`
+		Purpose string `json:"purpose"`
+		Value   string `json:"value"`
+		TOTP    string `json:"totp"`
+		Section *struct {
+			ID string `json:"id"`
+		} `json:"section"`
+		PasswordDetails *struct {
+			Entropy   float64  `json:"entropy"`
+			Generated bool     `json:"generated"`
+			Strength  string   `json:"strength"`
+			History   []string `json:"history"`
+		} `json:"password_details"`
+	} `json:"fields"`
+	URLs []struct {
+		Primary bool   `json:"primary"`
+		Href    string `json:"href"`
+	} `json:"urls"`
+}
+
+// toV1 converts a v2 item into the v1 layout used throughout the package
+func (i opItemV2) toV1() opItem {
+	oi := opItem{
+		UUID:         i.ID,
+		TemplateUUID: templateUUID(i.Category),
+		Title:        i.Title,
+		CreatedAt:    i.CreatedAt,
+		UpdatedAt:    i.UpdatedAt,
+	}
+	oi.Overview.Tags = i.Tags
+	for n, u := range i.URLs {
+		if u.Primary || n == 0 {
+			oi.Overview.URL = u.Href
+		}
+	}
+	sections := make(map[string]int, len(i.Sections))
+	for _, s := range i.Sections {
+		sections[s.ID] = len(oi.Details.Sections)
+		oi.Details.Sections = append(oi.Details.Sections, opSection{Name: s.ID, Title: s.Label})
+	}
+	for _, field := range i.Fields {
+		if field.TOTP != "" {
+			oi.totp = field.TOTP
+		}
+		// fields which belong to a section are kept there as they are in v1
+		if field.Section != nil && field.Purpose == "" {
+			n, ok := sections[field.Section.ID]
+			if !ok {
+				n = len(oi.Details.Sections)
+				sections[field.Section.ID] = n
+				oi.Details.Sections = append(oi.Details.Sections, opSection{Name: field.Section.ID})
+			}
+			oi.Details.Sections[n].Fields = append(oi.Details.Sections[n].Fields, opSectionField{
+				K: strings.ToLower(field.Type),
+				N: field.ID,
+				T: field.Label,
+				V: field.Value,
+			})
+			continue
+		}
+		var name string
+		switch field.Purpose {
+		case "NOTES":
+			oi.Details.NotesPlain = field.Value
+			continue
+		case "USERNAME":
+			name = "username"
+		case "PASSWORD":
+			name = "password"
+			if d := field.PasswordDetails; d != nil {
+				oi.strength = d.Strength
+				oi.Overview.PasswordEntropy = d.Entropy
+				oi.Overview.PasswordGenerated = d.Generated
+				// v2 doesn't record when passwords were changed
+				for _, h := range d.History {
+					oi.Details.PasswordHistory = append(oi.Details.PasswordHistory, opPasswordHistory{Value: h})
+				}
+			}
+		default:
+			name = field.Label
+		}
+		oi.Details.Fields = append(oi.Details.Fields, opField{Name: name, Type: strings.ToLower(field.Type), Value: field.Value})
+	}
+	return oi
+}
+
+// opTemplateV2 is an item template in the format read by version 2 of op
+type opTemplateV2 struct {
+	Sections []opTemplateSection `json:"sections,omitempty"`
+	Fields   []opTemplateField   `json:"fields,omitempty"`
+}
+
+type opTemplateSection struct {
+	ID    string `json:"id"`
+	Label string `json:"label,omitempty"`
+}
+
+type opTemplateField struct {
+	ID      string             `json:"id,omitempty"`
+	Type    string             `json:"type"`
+	Purpose string             `json:"purpose,omitempty"`
+	Label   string             `json:"label,omitempty"`
+	Value   string             `json:"value"`
+	Section *opTemplateSection `json:"section,omitempty"`
+}
+
+// v2FieldTypes maps the field types used by v1 to those used by v2
+var v2FieldTypes = map[string]string{"P": "CONCEALED", "E": "EMAIL", "U": "URL"}
+
+// toV2 converts details in the v1 layout into a template for v2, reversing
+// the conversion made by toV1. v2 doesn't accept password history.
+func (d opDetails) toV2() opTemplateV2 {
+	var t opTemplateV2
+	for _, field := range d.Fields {
+		f := opTemplateField{ID: field.Name, Type: "STRING", Label: field.Name, Value: field.Value}
+		if typ, ok := v2FieldTypes[field.Type]; ok {
+			f.Type = typ
+		}
+		switch field.Designation {
+		case "username":
+			f.Purpose = "USERNAME"
+		case "password":
+			f.Purpose = "PASSWORD"
+		}
+		t.Fields = append(t.Fields, f)
+	}
+	if d.Password != "" {
+		t.Fields = append(t.Fields, opTemplateField{ID: "password", Type: "CONCEALED", Purpose: "PASSWORD", Label: "password", Value: d.Password})
+	}
+	if d.NotesPlain != "" {
+		t.Fields = append(t.Fields, opTemplateField{ID: "notesPlain", Type: "STRING", Purpose: "NOTES", Label: "notesPlain", Value: d.NotesPlain})
+	}
+	for _, section := range d.Sections {
+		s := opTemplateSection{ID: section.Name, Label: section.Title}
+		t.Sections = append(t.Sections, s)
+		for _, field := range section.Fields {
+			t.Fields = append(t.Fields, opTemplateField{ID: field.N, Type: strings.ToUpper(field.K), Label: field.T, Value: field.V, Section: &opTemplateSection{ID: s.ID}})
+		}
+	}
+	return t
+}
+
+// Version returns the version of the op binary, such as "2.24.0". The version
+// is only looked up once per Op.
+func (o *Op) Version() (string, error) {
+	return o.opVersion(context.Background())
+}
+
+// opVersion runs op --version unless the version is already known
+func (o *Op) opVersion(ctx context.Context) (string, error) {
+	o.mu.RLock()
+	version := o.version
+	o.mu.RUnlock()
+	if version != "" {
+		return version, nil
+	}
+	out, err := o.RunOpContext(ctx, "--version")
+	if err != nil {
+		return "", fmt.Errorf("unable to determine op version: %w", err)
+	}
+	version = strings.TrimSpace(string(out))
+	o.mu.Lock()
+	o.version = version
+	o.mu.Unlock()
+	return version, nil
+}
+
+// isV2 reports whether the op binary is version 2 or later
+func (o *Op) isV2(ctx context.Context) (bool, error) {
+	version, err := o.opVersion(ctx)
+	if err != nil {
+		return false, err
+	}
+	major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	if err != nil {
+		return false, fmt.Errorf("unable to parse op version '%s': %v", version, err)
+	}
+	return major >= 2, nil
+}
+
+// itemCommand builds the arguments for an action against an object type,
+// taking into account the noun-verb ordering used by v2
+func itemCommand(v2 bool, action, itemType string, args ...string) []string {
+	if v2 {
+		return append([]string{itemType, action}, args...)
+	}
+	return append([]string{action, itemType}, args...)
+}
+
+// unmarshalItem parses item output from either version of op
+func unmarshalItem(data []byte, v2 bool) (oi opItem, err error) {
+	if !v2 {
+		err = json.Unmarshal(data, &oi)
+		// v1 only includes the title in the overview
+		if oi.Title == "" {
+			oi.Title = oi.Overview.Title
+		}
+		return oi, err
+	}
+	var i opItemV2
+	if err = json.Unmarshal(data, &i); err != nil {
+		return oi, err
+	}
+	return i.toV1(), nil
+}
@@ -0,0 +1,86 @@
+package op
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrVaultExists is returned by CreateVault when a vault with the same name
+// already exists
+var ErrVaultExists = errors.New("vault already exists")
+
+// vaultOptions holds the optional settings for a new vault
+type vaultOptions struct {
+	description string
+	icon        string
+}
+
+// VaultOpt sets an optional property of a vault created by CreateVault
+type VaultOpt func(*vaultOptions)
+
+// VaultDescription sets the description of a new vault
+func VaultDescription(description string) VaultOpt {
+	return func(v *vaultOptions) {
+		v.description = description
+	}
+}
+
+// VaultIcon sets the icon of a new vault, such as "buildings" or "vault-door"
+func VaultIcon(icon string) VaultOpt {
+	return func(v *vaultOptions) {
+		v.icon = icon
+	}
+}
+
+// CreateVault creates a vault with the given name. If a vault with the same
+// name is already accessible to the account, an error wrapping ErrVaultExists
+// is returned.
+func (o *Op) CreateVault(name string, opts ...VaultOpt) (Vault, error) {
+	var vo vaultOptions
+	for _, opt := range opts {
+		opt(&vo)
+	}
+	vaults, err := o.ListVaults()
+	if err != nil {
+		return Vault{}, err
+	}
+	for _, v := range vaults {
+		if v.Name == name {
+			return Vault{}, fmt.Errorf("unable to create vault '%s': %w", name, ErrVaultExists)
+		}
+	}
+	ctx := context.Background()
+	v2, err := o.isV2(ctx)
+	if err != nil {
+		return Vault{}, err
+	}
+	args := itemCommand(v2, "create", "vault", name)
+	if vo.description != "" {
+		args = append(args, "--description", vo.description)
+	}
+	if vo.icon != "" {
+		args = append(args, "--icon", vo.icon)
+	}
+	if v2 {
+		args = append(args, "--format", "json")
+	}
+	out, err := o.RunOpContext(ctx, args...)
+	if err != nil {
+		return Vault{}, err
+	}
+	// nothing is output when WithDryRun is used
+	if len(out) == 0 {
+		return Vault{Name: name}, nil
+	}
+	var created opVault
+	if err := json.Unmarshal(out, &created); err != nil {
+		return Vault{}, fmt.Errorf("unable to unmarshal vault data: %v", err)
+	}
+	uuid := created.UUID
+	if uuid == "" {
+		uuid = created.ID
+	}
+	return Vault{UUID: uuid, Name: created.Name}, nil
+}
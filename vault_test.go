@@ -0,0 +1,47 @@
+package op
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"testing"
+)
+
+func TestCreateVault(t *testing.T) {
+	configImpl = mockConfiger{}
+	tests := []struct {
+		name     string
+		runner   func(ctx context.Context, name string, args ...string) *exec.Cmd
+		wantArgs string
+	}{
+		{"V1", mockCmd, "[create vault Team --description Team secrets --icon buildings]"},
+		{"V2", mockCmdV2, "[vault create Team --description Team secrets --icon buildings --format json]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			recordCmd := func(ctx context.Context, name string, args ...string) *exec.Cmd {
+				got = args
+				return tt.runner(ctx, name, args...)
+			}
+			o, err := New(WithRunner(recordCmd))
+			if err != nil {
+				t.Fatal(err)
+			}
+			v, err := o.CreateVault("Team", VaultDescription("Team secrets"), VaultIcon("buildings"))
+			if err != nil {
+				t.Fatal("Unexpected error:", err)
+			}
+			if v.UUID != "newvault" || v.Name != "Team" {
+				t.Fatalf("Got vault: %v, want: {newvault Team}\n", v)
+			}
+			if fmt.Sprint(got) != tt.wantArgs {
+				t.Fatalf("Got args: %v, want: %s\n", got, tt.wantArgs)
+			}
+			if _, err := o.CreateVault("Shared"); !errors.Is(err, ErrVaultExists) {
+				t.Fatalf("Got error: %v, want: %v\n", err, ErrVaultExists)
+			}
+		})
+	}
+}